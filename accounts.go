@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+const (
+	deletionPolicyAnonymize = "anonymize"
+	deletionPolicyRemove    = "remove"
+
+	purgeInterval  = time.Hour
+	purgeBatchSize = 50
+)
+
+// purgeDeactivatedUsers deletes accounts whose deactivation grace period has
+// run out. Under the remove policy the user row and everything that
+// references it is deleted. Under the anonymize policy the row is kept as a
+// tombstone so the user's chirps and messages stay up without any personal
+// data attached. The grace period is counted from the database's NOW(), which
+// deactivated_at was set with.
+func (cfg *apiConfig) purgeDeactivatedUsers(ctx context.Context) (int, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTracedTx(tx)
+
+	userIDs, err := qtx.GetUsersDueForDeletion(ctx, database.GetUsersDueForDeletionParams{
+		GraceSeconds: cfg.deactivationGracePeriod.Seconds(),
+		BatchSize:    purgeBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userID := range userIDs {
+		switch cfg.deletionPolicy {
+		case deletionPolicyRemove:
+			if err := qtx.DeleteUser(ctx, userID); err != nil {
+				return 0, err
+			}
+		default:
+			if err := qtx.DeleteUserPersonalData(ctx, userID); err != nil {
+				return 0, err
+			}
+			if err := qtx.AnonymizeUser(ctx, userID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(userIDs), tx.Commit()
+}
+
+func (cfg *apiConfig) runUserPurger(ctx context.Context) {
+	cfg.runWorker(ctx, "user purger", purgeInterval, purgeBatchSize, cfg.purgeDeactivatedUsers)
+}
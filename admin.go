@@ -1,10 +1,54 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/auth"
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/notifications"
+	"github.com/debobrad579/chirpy/internal/validate"
+	"github.com/debobrad579/chirpy/internal/webhooks"
 )
 
+func (cfg *apiConfig) isAdmin(headers http.Header) bool {
+	adminKey, err := auth.GetAPIKey(headers)
+	return err == nil && cfg.adminKey != "" && adminKey == cfg.adminKey
+}
+
+// authenticateModerator accepts either the admin API key or the bearer token
+// of a user with the moderator flag. Requests made with the admin key act as
+// the system, so the returned ID is not valid. It responds to requests it
+// rejects.
+func (cfg *apiConfig) authenticateModerator(w http.ResponseWriter, r *http.Request) (uuid.NullUUID, bool) {
+	if cfg.isAdmin(r.Header) {
+		return uuid.NullUUID{}, true
+	}
+
+	userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+	if err != nil {
+		respondWithAuthError(w, r, err)
+		return uuid.NullUUID{}, false
+	}
+
+	user, err := cfg.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithAuthError(w, r, err)
+		return uuid.NullUUID{}, false
+	}
+	if !user.IsModerator {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.NullUUID{}, false
+	}
+
+	return uuid.NullUUID{UUID: userID, Valid: true}, true
+}
+
 func adminMux(cfg *apiConfig) *http.ServeMux {
 	mux := http.NewServeMux()
 
@@ -18,25 +62,456 @@ func adminMux(cfg *apiConfig) *http.ServeMux {
 					<p>Chirpy has been visited %d times!</p>
 				</body>
 			</html>
-		`, cfg.fileserverHits.Load())
+		`, cfg.metrics.fileserverHitsSinceReset())
 	})
 
 	mux.HandleFunc("POST /reset", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain")
 		cfg.resetHits()
 
 		if cfg.platform != "dev" {
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
 		}
 
-		if err := cfg.db.DeleteAllUsers(r.Context()); err != nil {
-			http.Error(w, "Failed to delete all users", http.StatusInternalServerError)
+		if err := cfg.store.DeleteAllUsers(r.Context()); err != nil {
+			respondWithServerError(w, r, "Failed to delete all users", err)
 			return
 		}
 
+		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "OK")
 	})
 
+	mux.HandleFunc("POST /webhooks", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Url    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		validateWebhook(&v, params.Url, params.Events)
+		if !checkValid(w, &v) {
+			return
+		}
+
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create webhook secret", err)
+			return
+		}
+
+		webhook, err := cfg.db.CreateWebhook(r.Context(), database.CreateWebhookParams{Url: params.Url, Secret: secret, Events: params.Events})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create webhook", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, toWebhookResponse(webhook))
+	})
+
+	mux.HandleFunc("GET /webhooks", func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		hooks, err := cfg.db.GetAdminWebhooks(r.Context())
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get webhooks", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(hooks, func(hook database.GetAdminWebhooksRow) webhookResponse {
+			return toWebhookRowResponse(database.GetWebhookRow(hook))
+		}))
+	})
+
+	mux.HandleFunc("DELETE /webhooks/{webhookID}", func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "webhookID is not a uuid")
+			return
+		}
+
+		if err := cfg.db.DeleteWebhook(r.Context(), webhookID); err != nil {
+			respondWithServerError(w, r, "Failed deleting webhook", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /webhooks/{webhookID}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "webhookID is not a uuid")
+			return
+		}
+
+		if _, err := cfg.db.GetWebhook(r.Context(), webhookID); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Webhook not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get webhook", err)
+			return
+		}
+
+		deliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{WebhookID: webhookID, Limit: deliveryLogLimit})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get webhook deliveries", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(deliveries, toWebhookDeliveryResponse))
+	})
+
+	mux.HandleFunc("GET /reports", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := cfg.authenticateModerator(w, r); !ok {
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = reportOpen
+		}
+		if status != reportOpen && status != reportDismissed && status != reportActioned {
+			respondWithError(w, http.StatusBadRequest, "Invalid status query param")
+			return
+		}
+
+		reports, err := cfg.db.GetReports(r.Context(), database.GetReportsParams{Status: status, Limit: reportsPageSize})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get reports", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(reports, toReportResponse))
+	})
+
+	mux.HandleFunc("POST /reports/{reportID}/actions", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Action string `json:"action"`
+			Note   string `json:"note"`
+		}
+
+		moderatorID, ok := cfg.authenticateModerator(w, r)
+		if !ok {
+			return
+		}
+
+		reportID, err := uuid.Parse(r.PathValue("reportID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "reportID is not a uuid")
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		v.Check(slices.Contains(reportActions, params.Action), "action", fmt.Sprintf("Action must be one of: %s", strings.Join(reportActions, ", ")))
+		if !checkValid(w, &v) {
+			return
+		}
+
+		report, err := cfg.db.GetReport(r.Context(), reportID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Report not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get report", err)
+			return
+		}
+
+		if report.Status != reportOpen {
+			respondWithError(w, http.StatusConflict, "Report has already been resolved")
+			return
+		}
+
+		if params.Action == actionHideChirp && !report.ChirpID.Valid {
+			respondWithError(w, http.StatusBadRequest, "Report is not about a chirp")
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to resolve report", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		resolution := reportActioned
+		if params.Action == actionDismiss {
+			resolution = reportDismissed
+		}
+
+		// Resolving first locks the report, so when two moderators act on it
+		// at once only one of them gets to apply an action.
+		n, err := qtx.ResolveReport(r.Context(), database.ResolveReportParams{ID: report.ID, Status: resolution, ResolvedBy: moderatorID})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to resolve report", err)
+			return
+		}
+		if n == 0 {
+			respondWithError(w, http.StatusConflict, "Report has already been resolved")
+			return
+		}
+
+		switch params.Action {
+		case actionDismiss:
+		case actionHideChirp:
+			if err := qtx.HideChirp(r.Context(), report.ChirpID.UUID); err != nil {
+				respondWithServerError(w, r, "Failed to hide chirp", err)
+				return
+			}
+		case actionWarnUser:
+			if _, err := qtx.CreateNotification(r.Context(), database.CreateNotificationParams{
+				UserID:  report.ReportedUserID,
+				Type:    notifications.TypeWarning,
+				ChirpID: report.ChirpID,
+			}); err != nil {
+				respondWithServerError(w, r, "Failed to warn user", err)
+				return
+			}
+		case actionSuspendUser:
+			if err := qtx.SetUserStatus(r.Context(), database.SetUserStatusParams{ID: report.ReportedUserID, Status: auth.StatusSuspended}); err != nil {
+				respondWithServerError(w, r, "Failed to suspend user", err)
+				return
+			}
+			if err := qtx.RevokeRefreshTokensForUser(r.Context(), report.ReportedUserID); err != nil {
+				respondWithServerError(w, r, "Failed to suspend user", err)
+				return
+			}
+		}
+
+		action, err := qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:   moderatorID,
+			ReportID:      uuid.NullUUID{UUID: report.ID, Valid: true},
+			Action:        params.Action,
+			TargetUserID:  uuid.NullUUID{UUID: report.ReportedUserID, Valid: true},
+			TargetChirpID: report.ChirpID,
+			Note:          params.Note,
+		})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to record moderation action", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to resolve report", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, action)
+	})
+
+	mux.HandleFunc("GET /audit-log", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := cfg.authenticateModerator(w, r); !ok {
+			return
+		}
+
+		actions, err := cfg.db.GetModerationActions(r.Context(), auditLogPageSize)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get audit log", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, actions)
+	})
+
+	mux.HandleFunc("PUT /moderators/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			IsModerator bool `json:"is_moderator"`
+		}
+
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		userID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		if _, err := cfg.store.GetUserByID(r.Context(), userID); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get user", err)
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to update moderator", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		if err := qtx.SetUserModerator(r.Context(), database.SetUserModeratorParams{ID: userID, IsModerator: params.IsModerator}); err != nil {
+			respondWithServerError(w, r, "Failed to update moderator", err)
+			return
+		}
+
+		action := actionRevokeModerator
+		if params.IsModerator {
+			action = actionGrantModerator
+		}
+
+		if _, err := qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			Action:       action,
+			TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		}); err != nil {
+			respondWithServerError(w, r, "Failed to record moderation action", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to update moderator", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /users/{userID}/export", func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		userID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
+			return
+		}
+
+		if _, err := cfg.store.GetUserByID(r.Context(), userID); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get user", err)
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create export", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		job, err := qtx.CreateExportJob(r.Context(), database.CreateExportJobParams{UserID: userID})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create export", err)
+			return
+		}
+
+		if _, err := qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			Action:       actionExportUser,
+			TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		}); err != nil {
+			respondWithServerError(w, r, "Failed to record moderation action", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to create export", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusAccepted, toExportJobResponse(database.GetExportJobRow(job)))
+	})
+
+	mux.HandleFunc("GET /exports/{exportID}", func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		exportID, err := uuid.Parse(r.PathValue("exportID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "exportID is not a uuid")
+			return
+		}
+
+		job, err := cfg.db.GetExportJob(r.Context(), exportID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Export not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get export", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toExportJobResponse(job))
+	})
+
+	mux.HandleFunc("GET /exports/{exportID}/download", func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.isAdmin(r.Header) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		exportID, err := uuid.Parse(r.PathValue("exportID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "exportID is not a uuid")
+			return
+		}
+
+		job, err := cfg.db.GetExportJob(r.Context(), exportID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Export not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get export", err)
+			return
+		}
+
+		cfg.serveExportArchive(w, r, job)
+	})
+
 	return mux
 }
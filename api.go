@@ -1,35 +1,157 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/debobrad579/chirpy/internal/auth"
 	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/importer"
+	"github.com/debobrad579/chirpy/internal/stream"
+	"github.com/debobrad579/chirpy/internal/validate"
+	"github.com/debobrad579/chirpy/internal/webhooks"
 )
 
+const maxRequestBodySize = 1 << 20
+
+// respondWithError responds with an RFC 9457 problem whose detail is msg.
 func respondWithError(w http.ResponseWriter, code int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	if err := json.NewEncoder(w).Encode(map[string]string{"error": msg}); err != nil {
-		log.Printf("Error encoding response: %s", err)
+	validate.WriteProblem(w, validate.NewProblem(code, msg))
+}
+
+// decodeJSON decodes the request body into dst. If the body is not acceptable
+// it responds with a problem and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if p := validate.DecodeJSON(w, r, dst, maxRequestBodySize); p != nil {
+		validate.WriteProblem(w, p)
+		return false
+	}
+	return true
+}
+
+// checkValid responds with the field errors collected by v and returns false
+// if there are any.
+func checkValid(w http.ResponseWriter, v *validate.Validator) bool {
+	if p := v.Problem(); p != nil {
+		validate.WriteProblem(w, p)
+		return false
 	}
+	return true
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		log.Printf("Error encoding response: %s", err)
+		slog.Error("Error encoding response", "error", err)
+	}
+}
+
+// cleanChirpBody validates the body of a new or edited chirp, whether it comes
+// from the API or an import, and masks profanity in it.
+func cleanChirpBody(body string) (string, error) {
+	if strings.TrimSpace(body) == "" {
+		return "", errors.New("Chirp is empty")
+	}
+	if len(body) > 140 {
+		return "", errors.New("Chirp is too long")
+	}
+
+	profaneWords := []string{
+		"kerfuffle",
+		"sharbert",
+		"fornax",
+	}
+
+	words := strings.Split(body, " ")
+	newWords := make([]string, len(words))
+
+	for i, word := range words {
+		if slices.Contains(profaneWords, strings.ToLower(word)) {
+			newWords[i] = "****"
+		} else {
+			newWords[i] = word
+		}
+	}
+
+	return strings.Join(newWords, " "), nil
+}
+
+func validateWebhook(v *validate.Validator, rawURL string, events []string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Check(false, "url", "Invalid webhook url")
+	} else {
+		v.Check(webhooks.PublicHost(u.Hostname()), "url", "Webhook url must not point at a private address")
+	}
+
+	v.Check(len(events) > 0, "events", "Webhook must subscribe to at least one event")
+
+	for i, event := range events {
+		v.Check(webhooks.ValidEvent(event), fmt.Sprintf("events.%d", i), fmt.Sprintf("Unknown webhook event %q", event))
+	}
+}
+
+// viewerID returns the ID of the user making the request, or uuid.Nil if the
+// request is anonymous. A request that sends credentials must send valid ones.
+func (cfg *apiConfig) viewerID(r *http.Request) (uuid.UUID, error) {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil, nil
+	}
+
+	return auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+}
+
+// respondWithAuthError answers a request whose user could not be
+// authenticated. Bad credentials and missing or inactive users get 401, but
+// failing to look the user up is a server error.
+func respondWithAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrInactiveUser) || errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
 	}
+	respondWithServerError(w, r, "Couldn't authenticate user", err)
+}
+
+// tokenUserID returns the user named by the request's access token. Only the
+// token's signature and expiry are checked, so it must not be used to
+// authorize anything.
+func (cfg *apiConfig) tokenUserID(r *http.Request) (uuid.UUID, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func validateCredentials(v *validate.Validator, email, password string) {
+	v.Required("email", email)
+	v.Email("email", email)
+	v.Required("password", password)
+}
+
+func validateReport(v *validate.Validator, reason, details string) {
+	v.Check(slices.Contains(reportReasons, reason), "reason", fmt.Sprintf("Reason must be one of: %s", strings.Join(reportReasons, ", ")))
+	v.Check(len(details) <= maxReportDetails, "details", "Report details are too long")
 }
 
 func apiMux(cfg *apiConfig) *http.ServeMux {
@@ -41,211 +163,1446 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 		fmt.Fprint(w, "OK")
 	})
 
-	mux.HandleFunc("POST /chirps", func(w http.ResponseWriter, r *http.Request) {
-		type parameters struct {
-			Body string `json:"body"`
+	mux.HandleFunc("GET /openapi.json", handleOpenAPISpec)
+
+	mux.HandleFunc("POST /chirps", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Body      string     `json:"body"`
+			PublishAt *time.Time `json:"publish_at"`
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		cleanedBody, err := cleanChirpBody(params.Body)
+		v.CheckError("body", err)
+		if params.PublishAt != nil {
+			v.Check(params.PublishAt.After(time.Now()), "publish_at", "Must be in the future")
+		}
+		if !checkValid(w, &v) {
+			return
+		}
+
+		createParams := database.CreateChirpParams{Body: cleanedBody, UserID: userID, IsPublished: true}
+		if params.PublishAt != nil {
+			createParams.PublishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+			createParams.IsPublished = false
+		}
+
+		if cfg.conn == nil {
+			// Without PostgreSQL there are no webhooks or notifications to
+			// send along with the chirp.
+			chirp, err := cfg.store.CreateChirp(r.Context(), createParams)
+			if err != nil {
+				respondWithServerError(w, r, "Failed to create chirp", err)
+				return
+			}
+			cfg.metrics.chirpsCreated.WithLabelValues(chirpSourceAPI).Inc()
+
+			respondWithJSON(w, http.StatusCreated, toChirpResponse(chirp))
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create chirp", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		chirp, err := qtx.CreateChirp(r.Context(), createParams)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create chirp", err)
+			return
+		}
+
+		if chirp.IsPublished {
+			if err := enqueueWebhookEvent(r.Context(), qtx, webhooks.EventChirpCreated, chirp.UserID, toChirpResponse(chirp)); err != nil {
+				respondWithServerError(w, r, "Failed to create chirp", err)
+				return
+			}
+
+			if err := notifyMentions(r.Context(), qtx, chirp); err != nil {
+				respondWithServerError(w, r, "Failed to create chirp", err)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to create chirp", err)
+			return
+		}
+		cfg.metrics.chirpsCreated.WithLabelValues(chirpSourceAPI).Inc()
+
+		respondWithJSON(w, http.StatusCreated, toChirpResponse(chirp))
+	})
+
+	mux.HandleFunc("POST /chirps/import", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		extendTimeouts(w, importTimeout)
+
+		format, err := importer.DetectFormat(r.URL.Query().Get("format"), r.Header.Get("Content-Type"), "")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Specify the format with ?format=jsonl|csv or a text/csv or application/x-ndjson Content-Type")
+			return
+		}
+
+		result, err := cfg.importChirps(r.Context(), userID, http.MaxBytesReader(w, r.Body, maxImportSize), format)
+		if err != nil {
+			if errors.Is(err, errImportStopped) {
+				respondWithServerError(w, r, "Failed to import chirps", err)
+				return
+			}
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondWithError(w, http.StatusRequestEntityTooLarge, "Import file is too large")
+				return
+			}
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("GET /chirps", func(w http.ResponseWriter, r *http.Request) {
+		viewerID, err := cfg.viewerID(r)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		sort := r.URL.Query().Get("sort")
+		if sort == "" {
+			sort = "asc"
+		}
+		if sort != "asc" && sort != "desc" {
+			respondWithError(w, http.StatusBadRequest, "Invalid sort query param")
+			return
+		}
+
+		authorIDString := r.URL.Query().Get("author_id")
+		if authorIDString == "" {
+			chirps, err := cfg.store.GetChirps(r.Context(), database.GetChirpsParams{ViewerID: viewerID, Sort: sort})
+			if err != nil {
+				respondWithServerError(w, r, "Failed to get chirps", err)
+				return
+			}
+
+			respondWithJSON(w, http.StatusOK, toResponses(chirps, toChirpResponse))
+			return
+		}
+
+		authorID, err := uuid.Parse(authorIDString)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Failed to parse authorID")
+			return
+		}
+
+		chirps, err := cfg.store.GetChirpsFromAuthor(r.Context(), database.GetChirpsFromAuthorParams{UserID: authorID, ViewerID: viewerID, Sort: sort})
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Invalid authorID")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get chirps", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(chirps, toChirpResponse))
+	})
+
+	mux.HandleFunc("GET /chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			return
+		}
+
+		chirp, err := cfg.store.GetChirp(r.Context(), chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get chirp", err)
+			return
+		}
+
+		viewerID, err := cfg.viewerID(r)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		// Blocks are kept in PostgreSQL, so without it nobody is blocked.
+		if cfg.conn != nil {
+			blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{BlockerID: chirp.UserID, BlockedID: viewerID})
+			if err != nil {
+				respondWithServerError(w, r, "Failed to get chirp", err)
+				return
+			}
+
+			if blocked {
+				respondWithError(w, http.StatusNotFound, "Chirp not found")
+				return
+			}
+		}
+
+		if chirp.HiddenAt.Valid {
+			respondWithError(w, http.StatusUnavailableForLegalReasons, "Chirp has been hidden by a moderator")
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toChirpResponse(chirp))
+	})
+
+	mux.HandleFunc("DELETE /chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			return
+		}
+
+		chirp, err := cfg.store.GetChirp(r.Context(), chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get chirp", err)
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if userID != chirp.UserID {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		if cfg.conn == nil {
+			if err := cfg.store.DeleteChirp(r.Context(), chirp.ID); err != nil {
+				respondWithServerError(w, r, "Failed deleting chirp", err)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed deleting chirp", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		if err := qtx.DeleteChirp(r.Context(), chirp.ID); err != nil {
+			respondWithServerError(w, r, "Failed deleting chirp", err)
+			return
+		}
+
+		if err := enqueueWebhookEvent(r.Context(), qtx, webhooks.EventChirpDeleted, chirp.UserID, toChirpResponse(chirp)); err != nil {
+			respondWithServerError(w, r, "Failed deleting chirp", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed deleting chirp", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /chirps/{chirpID}/report", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Reason  string `json:"reason"`
+			Details string `json:"details"`
+		}
+
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		validateReport(&v, params.Reason, params.Details)
+		if !checkValid(w, &v) {
+			return
+		}
+
+		chirp, err := cfg.store.GetChirp(r.Context(), chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get chirp", err)
+			return
+		}
+
+		if chirp.UserID == userID {
+			respondWithError(w, http.StatusBadRequest, "Cannot report your own chirp")
+			return
+		}
+
+		report, err := cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+			ReporterID:     userID,
+			ReportedUserID: chirp.UserID,
+			ChirpID:        uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Reason:         params.Reason,
+			Details:        params.Details,
+		})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create report", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, toReportResponse(report))
+	})
+
+	mux.HandleFunc("GET /chirps/scheduled", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		chirps, err := cfg.store.GetScheduledChirps(r.Context(), userID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get scheduled chirps", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(chirps, toChirpResponse))
+	})
+
+	mux.HandleFunc("PUT /chirps/scheduled/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Body      *string    `json:"body"`
+			PublishAt *time.Time `json:"publish_at"`
+		}
+
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		var cleanedBody string
+		if params.Body != nil {
+			cleanedBody, err = cleanChirpBody(*params.Body)
+			v.CheckError("body", err)
+		}
+		if params.PublishAt != nil {
+			v.Check(params.PublishAt.After(time.Now()), "publish_at", "Must be in the future")
+		}
+		if !checkValid(w, &v) {
+			return
+		}
+
+		chirp, err := cfg.store.GetScheduledChirp(r.Context(), chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Scheduled chirp not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get scheduled chirp", err)
+			return
+		}
+
+		if userID != chirp.UserID {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		updateParams := database.UpdateScheduledChirpParams{ID: chirp.ID, Body: chirp.Body, PublishAt: chirp.PublishAt}
+
+		if params.Body != nil {
+			updateParams.Body = cleanedBody
+		}
+
+		if params.PublishAt != nil {
+			updateParams.PublishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+		}
+
+		chirp, err = cfg.store.UpdateScheduledChirp(r.Context(), updateParams)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusConflict, "Chirp has already been published")
+				return
+			}
+			respondWithServerError(w, r, "Failed to update scheduled chirp", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toChirpResponse(chirp))
+	})
+
+	mux.HandleFunc("DELETE /chirps/scheduled/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		chirp, err := cfg.store.GetScheduledChirp(r.Context(), chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Scheduled chirp not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get scheduled chirp", err)
+			return
+		}
+
+		if userID != chirp.UserID {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		// The publisher may have published the chirp since it was read.
+		n, err := cfg.store.DeleteScheduledChirp(r.Context(), chirp.ID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed deleting scheduled chirp", err)
+			return
+		}
+		if n == 0 {
+			respondWithError(w, http.StatusConflict, "Chirp has already been published")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /conversations", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			MemberIDs []uuid.UUID `json:"member_ids"`
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var memberIDs []uuid.UUID
+		for _, memberID := range params.MemberIDs {
+			if memberID != userID && !slices.Contains(memberIDs, memberID) {
+				memberIDs = append(memberIDs, memberID)
+			}
+		}
+
+		var v validate.Validator
+		v.Check(len(memberIDs) > 0, "member_ids", "Conversation needs at least one other member")
+		v.Check(len(memberIDs)+1 <= maxConversationMembers, "member_ids", fmt.Sprintf("Conversation can have at most %d members", maxConversationMembers))
+		if !checkValid(w, &v) {
+			return
+		}
+
+		count, err := cfg.db.CountUsers(r.Context(), memberIDs)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to check members", err)
+			return
+		}
+
+		if count != int64(len(memberIDs)) {
+			respondWithError(w, http.StatusBadRequest, "Unknown member")
+			return
+		}
+
+		for _, memberID := range memberIDs {
+			blocked, err := cfg.db.BlockExistsBetween(r.Context(), database.BlockExistsBetweenParams{UserID: userID, OtherUserID: memberID})
+			if err != nil {
+				respondWithServerError(w, r, "Failed to check blocks", err)
+				return
+			}
+			if blocked {
+				respondWithError(w, http.StatusForbidden, "Cannot start a conversation with this user")
+				return
+			}
+		}
+
+		if len(memberIDs) == 1 {
+			conversation, err := cfg.db.GetDirectConversation(r.Context(), database.GetDirectConversationParams{UserID: userID, OtherUserID: memberIDs[0]})
+			if err == nil {
+				respondWithJSON(w, http.StatusOK, conversation)
+				return
+			}
+			if err != sql.ErrNoRows {
+				respondWithServerError(w, r, "Failed to get conversation", err)
+				return
+			}
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create conversation", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		conversation, err := qtx.CreateConversation(r.Context())
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create conversation", err)
+			return
+		}
+
+		for _, memberID := range append(memberIDs, userID) {
+			if err := qtx.AddConversationMember(r.Context(), database.AddConversationMemberParams{ConversationID: conversation.ID, UserID: memberID}); err != nil {
+				respondWithServerError(w, r, "Failed to create conversation", err)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to create conversation", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, conversation)
+	})
+
+	mux.HandleFunc("GET /conversations", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		conversations, err := cfg.db.GetConversationsForUser(r.Context(), userID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get conversations", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, conversations)
+	})
+
+	mux.HandleFunc("GET /conversations/{conversationID}", func(w http.ResponseWriter, r *http.Request) {
+		type returnVals struct {
+			database.Conversation
+			Members []conversationMemberResponse `json:"members"`
+		}
+
+		conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "conversationID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if _, err := cfg.db.GetConversationMember(r.Context(), database.GetConversationMemberParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get conversation", err)
+			return
+		}
+
+		conversation, err := cfg.db.GetConversation(r.Context(), conversationID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get conversation", err)
+			return
+		}
+
+		members, err := cfg.db.GetConversationMembers(r.Context(), conversationID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get conversation members", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, returnVals{conversation, toResponses(members, toConversationMemberResponse)})
+	})
+
+	mux.HandleFunc("GET /conversations/{conversationID}/messages", func(w http.ResponseWriter, r *http.Request) {
+		conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "conversationID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		// Without before, the query starts from the database's NOW(), which
+		// created_at was set with.
+		var before sql.NullTime
+		if beforeString := r.URL.Query().Get("before"); beforeString != "" {
+			t, err := time.Parse(time.RFC3339Nano, beforeString)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid before query param")
+				return
+			}
+			before = sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+
+		pageSize := messagesPageSize
+		if limitString := r.URL.Query().Get("limit"); limitString != "" {
+			limit, err := strconv.Atoi(limitString)
+			if err != nil || limit < 1 || limit > messagesPageSize {
+				respondWithError(w, http.StatusBadRequest, "Invalid limit query param")
+				return
+			}
+			pageSize = limit
+		}
+
+		if _, err := cfg.db.GetConversationMember(r.Context(), database.GetConversationMemberParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get conversation", err)
+			return
+		}
+
+		messages, err := cfg.db.GetMessages(r.Context(), database.GetMessagesParams{ConversationID: conversationID, Before: before, PageSize: int32(pageSize)})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get messages", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, messages)
+	})
+
+	mux.HandleFunc("POST /conversations/{conversationID}/messages", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Body string `json:"body"`
+		}
+
+		conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "conversationID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		v.Required("body", params.Body)
+		v.Check(len(params.Body) <= maxMessageLength, "body", "Message is too long")
+		if !checkValid(w, &v) {
+			return
+		}
+
+		if _, err := cfg.db.GetConversationMember(r.Context(), database.GetConversationMemberParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get conversation", err)
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to send message", err)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		message, err := qtx.CreateMessage(r.Context(), database.CreateMessageParams{ConversationID: conversationID, UserID: userID, Body: params.Body})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to send message", err)
+			return
+		}
+
+		if err := qtx.TouchConversation(r.Context(), conversationID); err != nil {
+			respondWithServerError(w, r, "Failed to send message", err)
+			return
+		}
+
+		if err := qtx.MarkConversationRead(r.Context(), database.MarkConversationReadParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			respondWithServerError(w, r, "Failed to send message", err)
+			return
+		}
+
+		if err := notifyMessage(r.Context(), qtx, message); err != nil {
+			respondWithServerError(w, r, "Failed to send message", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to send message", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, message)
+	})
+
+	mux.HandleFunc("POST /conversations/{conversationID}/read", func(w http.ResponseWriter, r *http.Request) {
+		conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "conversationID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if _, err := cfg.db.GetConversationMember(r.Context(), database.GetConversationMemberParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get conversation", err)
+			return
+		}
+
+		if err := cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			respondWithServerError(w, r, "Failed to mark conversation read", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("PUT /conversations/{conversationID}/mute", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Muted bool `json:"muted"`
+		}
+
+		conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "conversationID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		if _, err := cfg.db.GetConversationMember(r.Context(), database.GetConversationMemberParams{ConversationID: conversationID, UserID: userID}); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get conversation", err)
+			return
+		}
+
+		if err := cfg.db.SetConversationMuted(r.Context(), database.SetConversationMutedParams{ConversationID: conversationID, UserID: userID, Muted: params.Muted}); err != nil {
+			respondWithServerError(w, r, "Failed to update conversation", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /stream/chirps", func(w http.ResponseWriter, r *http.Request) {
+		filter := stream.Filter{Hashtag: r.URL.Query().Get("hashtag")}
+
+		if authorIDString := r.URL.Query().Get("author_id"); authorIDString != "" {
+			authorID, err := uuid.Parse(authorIDString)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Failed to parse authorID")
+				return
+			}
+			filter.AuthorID = authorID
+		}
+
+		viewerID, err := cfg.viewerID(r)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if viewerID != uuid.Nil {
+			hiddenAuthors, err := cfg.db.GetHiddenAuthors(r.Context(), viewerID)
+			if err != nil {
+				respondWithServerError(w, r, "Failed to get hidden authors", err)
+				return
+			}
+			filter.HiddenAuthors = hiddenAuthors
+		}
+
+		sub, missed := cfg.chirpStream.Subscribe(filter, r.Header.Get("Last-Event-ID"))
+		defer sub.Close()
+
+		disableTimeouts(w)
+		rc := http.NewResponseController(w)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		for _, chirp := range missed {
+			if err := stream.WriteEvent(w, chirp.ID, toChirpResponse(chirp)); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeatPeriod)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-cfg.shutdown:
+				// Clients reconnect with Last-Event-ID and miss nothing.
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			case chirp, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if err := stream.WriteEvent(w, chirp.ID, toChirpResponse(chirp)); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	mux.HandleFunc("GET /notifications", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		unreadOnly := r.URL.Query().Get("unread") == "true"
+
+		// Without before, the query starts from the database's NOW(), which
+		// created_at was set with.
+		var before sql.NullTime
+		if beforeString := r.URL.Query().Get("before"); beforeString != "" {
+			t, err := time.Parse(time.RFC3339Nano, beforeString)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid before query param")
+				return
+			}
+			before = sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+
+		pageSize := notificationsPageSize
+		if limitString := r.URL.Query().Get("limit"); limitString != "" {
+			limit, err := strconv.Atoi(limitString)
+			if err != nil || limit < 1 || limit > notificationsPageSize {
+				respondWithError(w, http.StatusBadRequest, "Invalid limit query param")
+				return
+			}
+			pageSize = limit
+		}
+
+		notifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+			UserID:     userID,
+			UnreadOnly: unreadOnly,
+			Before:     before,
+			PageSize:   int32(pageSize),
+		})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get notifications", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(notifications, toNotificationResponse))
+	})
+
+	mux.HandleFunc("POST /notifications/read", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if err := cfg.db.MarkAllNotificationsRead(r.Context(), userID); err != nil {
+			respondWithServerError(w, r, "Failed to mark notifications read", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /notifications/{notificationID}/read", func(w http.ResponseWriter, r *http.Request) {
+		notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "notificationID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		n, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{ID: notificationID, UserID: userID})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to mark notification read", err)
+			return
+		}
+
+		if n == 0 {
+			respondWithError(w, http.StatusNotFound, "Notification not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		disableTimeouts(w)
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+
+		cfg.upgraded.Add(1)
+		defer cfg.upgraded.Done()
+
+		sub := cfg.notifications.Subscribe(userID)
+		defer sub.Close()
+
+		// Clients only receive on this socket; CloseRead handles their pings
+		// and close frames and cancels ctx once they go away.
+		ctx := conn.CloseRead(r.Context())
+
+		heartbeat := time.NewTicker(websocketHeartbeatPeriod)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-cfg.shutdown:
+				conn.Close(websocket.StatusGoingAway, "Server is shutting down")
+				return
+			case <-heartbeat.C:
+				pingCtx, cancel := context.WithTimeout(ctx, websocketWriteTimeout)
+				err := conn.Ping(pingCtx)
+				cancel()
+				if err != nil {
+					return
+				}
+			case notification, ok := <-sub.C:
+				if !ok {
+					conn.Close(websocket.StatusTryAgainLater, "Client is too slow")
+					return
+				}
+				writeCtx, cancel := context.WithTimeout(ctx, websocketWriteTimeout)
+				err := wsjson.Write(writeCtx, conn, toNotificationResponse(notification))
+				cancel()
+				if err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		validateCredentials(&v, params.Email, params.Password)
+		if !checkValid(w, &v) {
+			return
+		}
+
+		hashedPassword, err := auth.HashPassword(params.Password)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to hash password", err)
+			return
+		}
+
+		user, err := cfg.store.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: hashedPassword})
+		if err != nil {
+			respondWithServerError(w, r, "Failed creating user", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, user)
+	})
+
+	mux.HandleFunc("PUT /users", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		validateCredentials(&v, params.Email, params.Password)
+		if !checkValid(w, &v) {
+			return
+		}
+
+		hashedPassword, err := auth.HashPassword(params.Password)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to hash password", err)
+			return
+		}
+
+		user, err := cfg.store.UpdateUser(r.Context(), database.UpdateUserParams{ID: userID, Email: params.Email, HashedPassword: hashedPassword})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to update user", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, user)
+	})
+
+	mux.HandleFunc("GET /blocks", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		blocks, err := cfg.db.GetBlocks(r.Context(), userID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get blocks", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, blocks)
+	})
+
+	mux.HandleFunc("POST /users/{userID}/block", func(w http.ResponseWriter, r *http.Request) {
+		blockedID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if blockedID == userID {
+			respondWithError(w, http.StatusBadRequest, "Cannot block yourself")
+			return
+		}
+
+		if err := cfg.db.CreateBlock(r.Context(), database.CreateBlockParams{BlockerID: userID, BlockedID: blockedID}); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				respondWithError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to block user", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /users/{userID}/block", func(w http.ResponseWriter, r *http.Request) {
+		blockedID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		if err := cfg.db.DeleteBlock(r.Context(), database.DeleteBlockParams{BlockerID: userID, BlockedID: blockedID}); err != nil {
+			respondWithServerError(w, r, "Failed to unblock user", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /mutes", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
 		}
 
-		userID, err := auth.AuthenticateUser(r.Header, cfg.tokenSecret)
+		mutes, err := cfg.db.GetMutes(r.Context(), userID)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			respondWithServerError(w, r, "Failed to get mutes", err)
 			return
 		}
 
-		var params parameters
-		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Unauthorized")
+		respondWithJSON(w, http.StatusOK, mutes)
+	})
+
+	mux.HandleFunc("POST /users/{userID}/mute", func(w http.ResponseWriter, r *http.Request) {
+		mutedID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
 			return
 		}
 
-		if len(params.Body) > 140 {
-			respondWithError(w, http.StatusBadRequest, "Chirp is too long")
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
 			return
 		}
 
-		profaneWords := []string{
-			"kerfuffle",
-			"sharbert",
-			"fornax",
+		if mutedID == userID {
+			respondWithError(w, http.StatusBadRequest, "Cannot mute yourself")
+			return
 		}
 
-		words := strings.Split(params.Body, " ")
-		newWords := make([]string, len(words))
-
-		for i, word := range words {
-			if slices.Contains(profaneWords, strings.ToLower(word)) {
-				newWords[i] = "****"
-			} else {
-				newWords[i] = word
+		if err := cfg.db.CreateMute(r.Context(), database.CreateMuteParams{MuterID: userID, MutedID: mutedID}); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				respondWithError(w, http.StatusNotFound, "User not found")
+				return
 			}
+			respondWithServerError(w, r, "Failed to mute user", err)
+			return
 		}
 
-		cleanedBody := strings.Join(newWords, " ")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /users/{userID}/mute", func(w http.ResponseWriter, r *http.Request) {
+		mutedID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
+			return
+		}
 
-		chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{Body: cleanedBody, UserID: userID})
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create chirp")
+			respondWithAuthError(w, r, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, chirp)
+		if err := cfg.db.DeleteMute(r.Context(), database.DeleteMuteParams{MuterID: userID, MutedID: mutedID}); err != nil {
+			respondWithServerError(w, r, "Failed to unmute user", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
 	})
 
-	mux.HandleFunc("GET /chirps", func(w http.ResponseWriter, r *http.Request) {
-		sort := r.URL.Query().Get("sort")
-		if sort == "" {
-			sort = "asc"
+	mux.HandleFunc("POST /users/{userID}/report", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Reason  string `json:"reason"`
+			Details string `json:"details"`
 		}
-		if sort != "asc" && sort != "desc" {
-			respondWithError(w, http.StatusBadRequest, "Invalid sort query param")
+
+		reportedID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "userID is not a uuid")
 			return
 		}
 
-		authorIDString := r.URL.Query().Get("author_id")
-		if authorIDString == "" {
-			chirps, err := cfg.db.GetChirps(r.Context(), sort)
-			if err != nil {
-				respondWithError(w, http.StatusInternalServerError, "Failed to get chirps")
-				return
-			}
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
 
-			respondWithJSON(w, http.StatusOK, chirps)
+		var params parameters
+		if !decodeJSON(w, r, &params) {
 			return
 		}
 
-		authorID, err := uuid.Parse(authorIDString)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Failed to parse authorID")
+		var v validate.Validator
+		validateReport(&v, params.Reason, params.Details)
+		if !checkValid(w, &v) {
 			return
 		}
 
-		chirps, err := cfg.db.GetChirpsFromAuthor(r.Context(), database.GetChirpsFromAuthorParams{UserID: authorID, Column2: sort})
+		if reportedID == userID {
+			respondWithError(w, http.StatusBadRequest, "Cannot report yourself")
+			return
+		}
 
-		if err != nil {
+		if _, err := cfg.store.GetUserByID(r.Context(), reportedID); err != nil {
 			if err == sql.ErrNoRows {
-				respondWithError(w, http.StatusNotFound, "Invalid authorID")
+				respondWithError(w, http.StatusNotFound, "User not found")
 				return
 			}
-			respondWithError(w, http.StatusInternalServerError, "Failed to get chirps")
+			respondWithServerError(w, r, "Failed to get user", err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, chirps)
-	})
-
-	mux.HandleFunc("GET /chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
-		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		report, err := cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+			ReporterID:     userID,
+			ReportedUserID: reportedID,
+			Reason:         params.Reason,
+			Details:        params.Details,
+		})
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			respondWithServerError(w, r, "Failed to create report", err)
 			return
 		}
 
-		chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+		respondWithJSON(w, http.StatusCreated, toReportResponse(report))
+	})
+
+	mux.HandleFunc("POST /users/me/deactivate", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Password string `json:"password"`
+		}
+
+		type returnVals struct {
+			DeleteAfter time.Time `json:"delete_after"`
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				respondWithError(w, http.StatusNotFound, "Chirp not found")
-				return
-			}
-			respondWithError(w, http.StatusInternalServerError, "Failed to get chirp")
+			respondWithAuthError(w, r, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, chirp)
-	})
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
 
-	mux.HandleFunc("DELETE /chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
-		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		user, err := cfg.store.GetUserByID(r.Context(), userID)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "chirpID is not a uuid")
+			respondWithServerError(w, r, "Failed to get user", err)
 			return
 		}
 
-		chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+		ok, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				respondWithError(w, http.StatusNotFound, "Chirp not found")
-				return
-			}
-			respondWithError(w, http.StatusInternalServerError, "Failed to get chirp")
+			respondWithServerError(w, r, "Failed to check password hash", err)
+			return
+		}
+
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "Password is incorrect")
 			return
 		}
 
-		userID, err := auth.AuthenticateUser(r.Header, cfg.tokenSecret)
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			respondWithServerError(w, r, "Failed to deactivate account", err)
 			return
 		}
+		defer tx.Rollback()
 
-		if userID != chirp.UserID {
-			respondWithError(w, http.StatusForbidden, "Forbidden")
+		qtx := cfg.db.WithTracedTx(tx)
+
+		if err := qtx.DeactivateUser(r.Context(), userID); err != nil {
+			respondWithServerError(w, r, "Failed to deactivate account", err)
 			return
 		}
 
-		if err := cfg.db.DeleteChirp(r.Context(), chirp.ID); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed deleting chirp")
+		if err := qtx.RevokeRefreshTokensForUser(r.Context(), userID); err != nil {
+			respondWithServerError(w, r, "Failed to deactivate account", err)
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
+		if err := tx.Commit(); err != nil {
+			respondWithServerError(w, r, "Failed to deactivate account", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, returnVals{time.Now().Add(cfg.deactivationGracePeriod).UTC()})
 	})
 
-	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
-		type parameters struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
+	mux.HandleFunc("POST /users/me/export", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
 		}
 
-		var params parameters
-		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		job, err := cfg.db.CreateExportJob(r.Context(), database.CreateExportJobParams{
+			UserID:      userID,
+			RequestedBy: uuid.NullUUID{UUID: userID, Valid: true},
+		})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create export", err)
 			return
 		}
 
-		hashedPassword, err := auth.HashPassword(params.Password)
+		respondWithJSON(w, http.StatusAccepted, toExportJobResponse(database.GetExportJobRow(job)))
+	})
+
+	mux.HandleFunc("GET /users/me/exports/{exportID}", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+			respondWithAuthError(w, r, err)
 			return
 		}
 
-		user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: hashedPassword})
+		exportID, err := uuid.Parse(r.PathValue("exportID"))
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed creating user")
+			respondWithError(w, http.StatusBadRequest, "exportID is not a uuid")
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, user)
-	})
+		job, err := cfg.db.GetExportJob(r.Context(), exportID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Export not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get export", err)
+			return
+		}
 
-	mux.HandleFunc("PUT /users", func(w http.ResponseWriter, r *http.Request) {
-		type parameters struct {
-			Email    string
-			Password string
+		if job.UserID != userID {
+			respondWithError(w, http.StatusNotFound, "Export not found")
+			return
 		}
 
-		userID, err := auth.AuthenticateUser(r.Header, cfg.tokenSecret)
+		respondWithJSON(w, http.StatusOK, toExportJobResponse(job))
+	})
+
+	mux.HandleFunc("GET /users/me/exports/{exportID}/download", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			respondWithAuthError(w, r, err)
 			return
 		}
 
-		var params parameters
-		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		exportID, err := uuid.Parse(r.PathValue("exportID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "exportID is not a uuid")
+			return
 		}
 
-		hashedPassword, err := auth.HashPassword(params.Password)
+		job, err := cfg.db.GetExportJob(r.Context(), exportID)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Export not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get export", err)
 			return
 		}
 
-		user, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{ID: userID, Email: params.Email, HashedPassword: hashedPassword})
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+		if job.UserID != userID {
+			respondWithError(w, http.StatusNotFound, "Export not found")
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, user)
+		cfg.serveExportArchive(w, r, job)
 	})
 
 	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
@@ -265,51 +1622,78 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 		}
 
 		var params parameters
-		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		v.Required("email", params.Email)
+		v.Required("password", params.Password)
+		if !checkValid(w, &v) {
 			return
 		}
 
-		user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+		user, err := cfg.store.GetUserByEmail(r.Context(), params.Email)
 		if err != nil {
 			if err == sql.ErrNoRows {
+				cfg.metrics.logins.WithLabelValues(loginFailure).Inc()
 				respondWithError(w, http.StatusUnauthorized, "Email or password is incorrect")
 				return
 			}
-			respondWithError(w, http.StatusInternalServerError, "Failed to get user")
+			respondWithServerError(w, r, "Failed to get user", err)
 			return
 		}
 
 		ok, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
 
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to check password hash")
+			respondWithServerError(w, r, "Failed to check password hash", err)
 			return
 		}
 
 		if !ok {
+			cfg.metrics.logins.WithLabelValues(loginFailure).Inc()
+			respondWithError(w, http.StatusUnauthorized, "Email or password is incorrect")
+			return
+		}
+
+		switch user.Status {
+		case auth.StatusSuspended:
+			cfg.metrics.logins.WithLabelValues(loginFailure).Inc()
+			respondWithError(w, http.StatusForbidden, "Account is suspended")
+			return
+		case auth.StatusDeactivated:
+			if err := cfg.store.ReactivateUser(r.Context(), user.ID); err != nil {
+				respondWithServerError(w, r, "Failed to reactivate account", err)
+				return
+			}
+			user.Status = auth.StatusActive
+		case auth.StatusActive:
+		default:
+			cfg.metrics.logins.WithLabelValues(loginFailure).Inc()
 			respondWithError(w, http.StatusUnauthorized, "Email or password is incorrect")
 			return
 		}
 
 		token, err := auth.MakeJWT(user.ID, cfg.tokenSecret, 1*time.Hour)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create access token")
+			respondWithServerError(w, r, "Failed to create access token", err)
 			return
 		}
 
 		refreshTokenString, err := auth.MakeRefreshToken()
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create refresh token")
+			respondWithServerError(w, r, "Failed to create refresh token", err)
 			return
 		}
 
-		refreshToken, err := cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{Token: refreshTokenString, UserID: user.ID, ExpiresAt: time.Now().Add(60 * 24 * time.Hour)})
+		refreshToken, err := cfg.store.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{Token: refreshTokenString, UserID: user.ID, ExpiresAt: time.Now().Add(60 * 24 * time.Hour)})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create refresh token")
+			respondWithServerError(w, r, "Failed to create refresh token", err)
 			return
 		}
 
+		cfg.metrics.logins.WithLabelValues(loginSuccess).Inc()
 		respondWithJSON(w, http.StatusOK, returnVals{user.ID, user.CreatedAt, user.UpdatedAt, user.Email, user.IsChirpyRed, token, refreshToken.Token})
 	})
 
@@ -324,13 +1708,13 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 			Token string `json:"token"`
 		}
 
-		refreshToken, err := cfg.db.GetRefreshToken(r.Context(), token)
+		refreshToken, err := cfg.store.GetRefreshToken(r.Context(), token)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
 				return
 			}
-			respondWithError(w, http.StatusInternalServerError, "Failed to get refresh token")
+			respondWithServerError(w, r, "Failed to get refresh token", err)
 			return
 		}
 
@@ -339,9 +1723,20 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 			return
 		}
 
+		status, err := cfg.store.GetUserStatus(r.Context(), refreshToken.UserID)
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get user", err)
+			return
+		}
+
+		if status != auth.StatusActive {
+			respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+
 		accessToken, err := auth.MakeJWT(refreshToken.UserID, cfg.tokenSecret, 1*time.Hour)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create access token")
+			respondWithServerError(w, r, "Failed to create access token", err)
 			return
 		}
 
@@ -355,18 +1750,154 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 			return
 		}
 
-		if err := cfg.db.RevokeRefreshToken(r.Context(), token); err != nil {
+		n, err := cfg.store.RevokeRefreshToken(r.Context(), token)
+		if err != nil {
+			respondWithServerError(w, r, "Failed revoking refresh token", err)
+			return
+		}
+
+		if n == 0 {
+			respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /webhooks", func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			Url    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		var params parameters
+		if !decodeJSON(w, r, &params) {
+			return
+		}
+
+		var v validate.Validator
+		validateWebhook(&v, params.Url, params.Events)
+		if !checkValid(w, &v) {
+			return
+		}
+
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create webhook secret", err)
+			return
+		}
+
+		webhook, err := cfg.db.CreateWebhook(r.Context(), database.CreateWebhookParams{
+			UserID: uuid.NullUUID{UUID: userID, Valid: true},
+			Url:    params.Url,
+			Secret: secret,
+			Events: params.Events,
+		})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to create webhook", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, toWebhookResponse(webhook))
+	})
+
+	mux.HandleFunc("GET /webhooks", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		hooks, err := cfg.db.GetWebhooksForUser(r.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get webhooks", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(hooks, func(hook database.GetWebhooksForUserRow) webhookResponse {
+			return toWebhookRowResponse(database.GetWebhookRow(hook))
+		}))
+	})
+
+	mux.HandleFunc("DELETE /webhooks/{webhookID}", func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "webhookID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		webhook, err := cfg.db.GetWebhook(r.Context(), webhookID)
+		if err != nil {
 			if err == sql.ErrNoRows {
-				respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+				respondWithError(w, http.StatusNotFound, "Webhook not found")
 				return
 			}
-			respondWithError(w, http.StatusInternalServerError, "Failed revoking refresh token")
+			respondWithServerError(w, r, "Failed to get webhook", err)
+			return
+		}
+
+		if webhook.UserID.UUID != userID {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		if err := cfg.db.DeleteWebhook(r.Context(), webhook.ID); err != nil {
+			respondWithServerError(w, r, "Failed deleting webhook", err)
 			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 	})
 
+	mux.HandleFunc("GET /webhooks/{webhookID}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "webhookID is not a uuid")
+			return
+		}
+
+		userID, err := auth.AuthenticateUser(r.Context(), r.Header, cfg.tokenSecret, cfg.store)
+		if err != nil {
+			respondWithAuthError(w, r, err)
+			return
+		}
+
+		webhook, err := cfg.db.GetWebhook(r.Context(), webhookID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusNotFound, "Webhook not found")
+				return
+			}
+			respondWithServerError(w, r, "Failed to get webhook", err)
+			return
+		}
+
+		if webhook.UserID.UUID != userID {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		deliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: deliveryLogLimit})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to get webhook deliveries", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, toResponses(deliveries, toWebhookDeliveryResponse))
+	})
+
 	mux.HandleFunc("POST /polka/webhooks", func(w http.ResponseWriter, r *http.Request) {
 		type parameters struct {
 			Event string `json:"event"`
@@ -381,8 +1912,10 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 			return
 		}
 
+		// Polka may add fields to its payload at any time, so unknown fields
+		// are ignored here.
 		var params parameters
-		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&params); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
@@ -398,11 +1931,53 @@ func apiMux(cfg *apiConfig) *http.ServeMux {
 			return
 		}
 
-		if err := cfg.db.UpgradeUser(r.Context(), userID); err != nil {
-			if err == sql.ErrNoRows {
+		if cfg.conn == nil {
+			n, err := cfg.store.UpgradeUser(r.Context(), userID)
+			if err != nil {
+				logServerError(r, "Failed to upgrade user", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if n == 0 {
 				w.WriteHeader(http.StatusNotFound)
 				return
 			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		tx, err := cfg.conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			logServerError(r, "Failed to upgrade user", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.db.WithTracedTx(tx)
+
+		n, err := qtx.UpgradeUser(r.Context(), userID)
+		if err != nil {
+			logServerError(r, "Failed to upgrade user", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if n == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := enqueueWebhookEvent(r.Context(), qtx, webhooks.EventUserUpgraded, userID, map[string]uuid.UUID{"user_id": userID}); err != nil {
+			logServerError(r, "Failed to enqueue webhook event", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			logServerError(r, "Failed to upgrade user", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
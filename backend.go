@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/sqlite"
+	"github.com/debobrad579/chirpy/internal/store"
+)
+
+const (
+	// memoryDBURL keeps all data in memory, so Chirpy can be tried out with
+	// nothing else installed. Everything is lost when the server stops.
+	memoryDBURL = "memory:"
+	// sqliteScheme starts a DB_URL naming a SQLite database file, as in
+	// sqlite:chirpy.db or sqlite:///var/lib/chirpy/chirpy.db.
+	sqliteScheme = "sqlite:"
+)
+
+// backend is where Chirpy keeps its data.
+type backend struct {
+	store store.Store
+	// db is nil for the memory backend.
+	db         *sql.DB
+	migrations *goose.Provider
+	// postgres is set if db is PostgreSQL, which every route other than
+	// storeRoutes needs.
+	postgres bool
+}
+
+// openBackend opens the backend that the scheme of dbURL picks. Anything that
+// is not a memory: or sqlite: URL is taken to be a PostgreSQL connection
+// string.
+func openBackend(dbURL string) (backend, error) {
+	if dbURL == memoryDBURL {
+		return backend{store: store.NewMemoryStore()}, nil
+	}
+
+	if path, ok := strings.CutPrefix(dbURL, sqliteScheme); ok {
+		// Both sqlite:/path and sqlite:///path are absolute.
+		path = strings.TrimPrefix(path, "//")
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return backend{}, err
+		}
+		migrations, err := newSQLiteMigrator(db)
+		if err != nil {
+			return backend{}, err
+		}
+		return backend{store: store.NewSQLiteStore(db), db: db, migrations: migrations}, nil
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return backend{}, err
+	}
+	migrations, err := newMigrator(db)
+	if err != nil {
+		return backend{}, err
+	}
+	return backend{store: database.New(database.Traced(db)), db: db, migrations: migrations, postgres: true}, nil
+}
+
+// storeRoutes lists the apiMux and adminMux patterns that need nothing but
+// cfg.store. They are the only ones served without PostgreSQL.
+var storeRoutes = map[string]bool{
+	"GET /healthz":                       true,
+	"GET /openapi.json":                  true,
+	"POST /users":                        true,
+	"PUT /users":                         true,
+	"POST /login":                        true,
+	"POST /refresh":                      true,
+	"POST /revoke":                       true,
+	"POST /chirps":                       true,
+	"GET /chirps":                        true,
+	"GET /chirps/{chirpID}":              true,
+	"DELETE /chirps/{chirpID}":           true,
+	"GET /chirps/scheduled":              true,
+	"PUT /chirps/scheduled/{chirpID}":    true,
+	"DELETE /chirps/scheduled/{chirpID}": true,
+	"POST /polka/webhooks":               true,
+	"GET /metrics":                       true,
+	"POST /reset":                        true,
+}
+
+// middlewareStoreRoutes answers 501 for any route of mux that is not in
+// storeRoutes.
+func middlewareStoreRoutes(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" && !storeRoutes[pattern] {
+			respondWithError(w, http.StatusNotImplemented, "Not available without PostgreSQL")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/notifications"
+	"github.com/debobrad579/chirpy/internal/stream"
+)
+
+// newBackendTestServer serves Chirpy the way it runs with dbURL, which must
+// not be a PostgreSQL database. The schema is migrated first, as with
+// MIGRATE_ON_START.
+func newBackendTestServer(t *testing.T, dbURL string) *testServer {
+	t.Helper()
+
+	backend, err := openBackend(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.db != nil {
+		t.Cleanup(func() { backend.db.Close() })
+	}
+	if backend.migrations != nil {
+		if _, err := backend.migrations.Up(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &apiConfig{
+		store:         backend.store,
+		platform:      "dev",
+		tokenSecret:   testTokenSecret,
+		polkaKey:      testPolkaKey,
+		adminKey:      testAdminKey,
+		chirpStream:   stream.NewBroker(chirpReplaySize),
+		notifications: notifications.NewHub(),
+		rateLimits:    noRateLimits{},
+		metrics:       newMetrics(backend.db),
+		migrations:    backend.migrations,
+		shutdown:      make(chan struct{}),
+	}
+
+	s := &testServer{
+		cfg: cfg,
+		srv: httptest.NewServer(cfg.middlewareRequestLog(cfg.routes())),
+		muxes: map[string]*http.ServeMux{
+			"/api/v1": apiMux(cfg),
+			"/admin":  adminMux(cfg),
+		},
+		covered: map[string]bool{},
+	}
+	t.Cleanup(s.srv.Close)
+	t.Cleanup(func() { close(cfg.shutdown) })
+	return s
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+func TestMemoryBackend(t *testing.T) {
+	testStoreRoutes(t, newBackendTestServer(t, memoryDBURL))
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	testStoreRoutes(t, newBackendTestServer(t, sqliteScheme+filepath.Join(t.TempDir(), "chirpy.db")))
+}
+
+// testStoreRoutes checks that s serves every storeRoutes entry and answers 501
+// for every other route.
+func testStoreRoutes(t *testing.T, s *testServer) {
+
+	s.do(t, "GET", "/readyz", "", nil).expect(t, http.StatusOK)
+	s.do(t, "GET", "/api/v1/healthz", "", nil).expect(t, http.StatusOK)
+	s.do(t, "GET", "/api/v1/openapi.json", "", nil).expect(t, http.StatusOK)
+	s.do(t, "GET", "/admin/metrics", "", nil).expect(t, http.StatusOK)
+
+	u := s.signUp(t)
+	update := map[string]string{"email": "renamed-" + u.Email, "password": "new password"}
+	s.do(t, "PUT", "/api/v1/users", u.auth(), update).expect(t, http.StatusOK)
+	u.Email, u.password = update["email"], update["password"]
+	s.login(t, u)
+
+	s.do(t, "POST", "/api/v1/refresh", bearer(u.refreshToken), nil).expect(t, http.StatusOK)
+	s.do(t, "POST", "/api/v1/revoke", bearer(u.refreshToken), nil).expect(t, http.StatusNoContent)
+	s.do(t, "POST", "/api/v1/refresh", bearer(u.refreshToken), nil).expect(t, http.StatusUnauthorized)
+
+	chirp := s.chirp(t, u, "I am the one who knocks")
+	var chirps []chirpResponse
+	s.do(t, "GET", "/api/v1/chirps?author_id="+u.ID.String(), "", nil).expect(t, http.StatusOK).decode(t, &chirps)
+	if len(chirps) != 1 || chirps[0].ID != chirp.ID {
+		t.Fatalf("GET /chirps returned %v, expected only %s", chirps, chirp.ID)
+	}
+	s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), u.auth(), nil).expect(t, http.StatusOK)
+	s.do(t, "DELETE", "/api/v1/chirps/"+chirp.ID.String(), u.auth(), nil).expect(t, http.StatusNoContent)
+	s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), "", nil).expect(t, http.StatusNotFound)
+
+	var scheduled chirpResponse
+	s.do(t, "POST", "/api/v1/chirps", u.auth(), map[string]string{"body": "Later", "publish_at": "2100-01-01T00:00:00Z"}).
+		expect(t, http.StatusCreated).decode(t, &scheduled)
+	s.do(t, "GET", "/api/v1/chirps/scheduled", u.auth(), nil).expect(t, http.StatusOK).decode(t, &chirps)
+	if len(chirps) != 1 || chirps[0].ID != scheduled.ID {
+		t.Fatalf("GET /chirps/scheduled returned %v, expected only %s", chirps, scheduled.ID)
+	}
+	s.do(t, "PUT", "/api/v1/chirps/scheduled/"+scheduled.ID.String(), u.auth(), map[string]string{"body": "Even later"}).
+		expect(t, http.StatusOK)
+	s.do(t, "DELETE", "/api/v1/chirps/scheduled/"+scheduled.ID.String(), u.auth(), nil).expect(t, http.StatusNoContent)
+
+	s.do(t, "POST", "/api/v1/polka/webhooks", apiKey(testPolkaKey), map[string]any{"event": "user.upgraded", "data": map[string]string{"user_id": u.ID.String()}}).
+		expect(t, http.StatusNoContent)
+	if user, err := s.cfg.store.GetUserByID(t.Context(), u.ID); err != nil || !user.IsChirpyRed {
+		t.Fatalf("user is %+v, %v after the upgrade", user, err)
+	}
+
+	s.do(t, "POST", "/admin/reset", "", nil).expect(t, http.StatusOK)
+	s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": u.Email, "password": u.password}).
+		expect(t, http.StatusUnauthorized)
+
+	var routes []string
+	for prefix, file := range map[string][2]string{"/api/v1": {"api.go", "apiMux"}, "/admin": {"admin.go", "adminMux"}} {
+		for _, pattern := range registeredRoutes(t, file[0], file[1]) {
+			routes = append(routes, pattern)
+			if storeRoutes[pattern] {
+				if !s.covered[mountedRoute(prefix, pattern)] {
+					t.Errorf("%s is served without PostgreSQL but not tested", pattern)
+				}
+				continue
+			}
+			method, path, _ := strings.Cut(pattern, " ")
+			s.do(t, method, prefix+pathParam.ReplaceAllString(path, uuid.NewString()), u.auth(), nil).
+				expect(t, http.StatusNotImplemented)
+		}
+	}
+	for pattern := range storeRoutes {
+		if !slices.Contains(routes, pattern) {
+			t.Errorf("storeRoutes has %s, which is not a route", pattern)
+		}
+	}
+}
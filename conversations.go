@@ -0,0 +1,7 @@
+package main
+
+const (
+	maxConversationMembers = 8
+	maxMessageLength       = 1000
+	messagesPageSize       = 50
+)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/export"
+)
+
+const (
+	exportPending   = "pending"
+	exportCompleted = "completed"
+	exportFailed    = "failed"
+
+	exportInterval  = 10 * time.Second
+	exportRetention = 7 * 24 * time.Hour
+)
+
+// processExportJob builds the archive for the oldest pending export job. The
+// job row stays locked while the archive is generated so that only one
+// instance works on it.
+func (cfg *apiConfig) processExportJob(ctx context.Context) (int, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTracedTx(tx)
+
+	job, err := qtx.ClaimExportJob(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	// Failed jobs are kept as long as archives, so that they are swept too.
+	expiresAt := sql.NullTime{Time: time.Now().Add(exportRetention), Valid: true}
+
+	var buf bytes.Buffer
+	archive, err := export.Collect(ctx, qtx, job.UserID)
+	if err == nil {
+		err = archive.Write(&buf)
+	}
+
+	if err != nil {
+		if err := qtx.FailExportJob(ctx, database.FailExportJobParams{
+			ID:        job.ID,
+			Error:     sql.NullString{String: err.Error(), Valid: true},
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			return 0, err
+		}
+		return 1, tx.Commit()
+	}
+
+	if err := qtx.CompleteExportJob(ctx, database.CompleteExportJobParams{
+		ID:        job.ID,
+		Archive:   buf.Bytes(),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return 0, err
+	}
+
+	return 1, tx.Commit()
+}
+
+func (cfg *apiConfig) runExporter(ctx context.Context) {
+	cfg.runWorker(ctx, "exporter", exportInterval, 1, func(ctx context.Context) (int, error) {
+		if err := cfg.db.DeleteExpiredExportJobs(ctx); err != nil {
+			return 0, err
+		}
+		return cfg.processExportJob(ctx)
+	})
+}
+
+// serveExportArchive writes the archive of a completed export job as a ZIP
+// download.
+func (cfg *apiConfig) serveExportArchive(w http.ResponseWriter, r *http.Request, job database.GetExportJobRow) {
+	if job.Status != exportCompleted {
+		respondWithError(w, http.StatusConflict, "Export is not ready")
+		return
+	}
+
+	archive, err := cfg.db.GetExportArchive(r.Context(), job.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusGone, "Export has expired")
+			return
+		}
+		respondWithServerError(w, r, "Failed to get export", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, job.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	readinessTimeout = 2 * time.Second
+
+	// A worker is unhealthy after this many failed runs in a row, or when it
+	// has not finished a run in this many intervals.
+	workerFailureThreshold = 3
+	workerStallIntervals   = 3
+
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+type workerStatus struct {
+	// deadline is how long the worker may go without reporting before it is
+	// considered stuck, or zero if it reports only when something changes.
+	deadline   time.Duration
+	lastReport time.Time
+	failures   int
+	lastErr    error
+}
+
+// workerHealth tracks how the background workers are doing, for /readyz.
+type workerHealth struct {
+	mu       sync.Mutex
+	statuses map[string]*workerStatus
+}
+
+func (h *workerHealth) register(name string, deadline time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.statuses == nil {
+		h.statuses = map[string]*workerStatus{}
+	}
+	h.statuses[name] = &workerStatus{deadline: deadline, lastReport: time.Now()}
+}
+
+// report records the outcome of a run of the named worker.
+func (h *workerHealth) report(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status, ok := h.statuses[name]
+	if !ok {
+		return
+	}
+	status.lastReport = time.Now()
+	status.lastErr = err
+	if err != nil {
+		status.failures++
+	} else {
+		status.failures = 0
+	}
+}
+
+func (h *workerHealth) checks(now time.Time) map[string]check {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	checks := map[string]check{}
+	for name, status := range h.statuses {
+		c := check{Status: checkOK}
+		switch {
+		case status.failures >= workerFailureThreshold:
+			c = check{Status: checkFail, Detail: fmt.Sprintf("failed %d times in a row: %s", status.failures, status.lastErr)}
+		case status.deadline > 0 && now.Sub(status.lastReport) > status.deadline:
+			c = check{Status: checkFail, Detail: fmt.Sprintf("has not run since %s", status.lastReport.UTC().Format(time.RFC3339))}
+		}
+		checks["worker:"+name] = c
+	}
+	return checks
+}
+
+type check struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type readiness struct {
+	Status string           `json:"status"`
+	Checks map[string]check `json:"checks"`
+}
+
+// checkReadiness runs every readiness check. The instance is ready only if
+// all of them pass.
+func (cfg *apiConfig) checkReadiness(ctx context.Context) readiness {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := cfg.workers.checks(time.Now())
+
+	checks["server"] = check{Status: checkOK}
+	if cfg.draining.Load() {
+		checks["server"] = check{Status: checkFail, Detail: "shutting down"}
+	}
+
+	// Without PostgreSQL there is no database to ping, and the memory
+	// backend has no schema either.
+	if cfg.conn != nil {
+		checks["database"] = check{Status: checkOK}
+		if err := cfg.conn.PingContext(ctx); err != nil {
+			checks["database"] = check{Status: checkFail, Detail: err.Error()}
+		}
+	}
+	if cfg.migrations != nil {
+		checks["schema"] = check{Status: checkOK}
+		if err := checkSchema(ctx, cfg.migrations); err != nil {
+			checks["schema"] = check{Status: checkFail, Detail: err.Error()}
+		}
+	}
+
+	result := readiness{Status: checkOK, Checks: checks}
+	for _, c := range checks {
+		if c.Status != checkOK {
+			result.Status = checkFail
+		}
+	}
+	return result
+}
+
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "OK")
+}
+
+func (cfg *apiConfig) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	result := cfg.checkReadiness(r.Context())
+	status := http.StatusOK
+	if result.Status != checkOK {
+		status = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, status, result)
+}
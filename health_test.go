@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerHealth(t *testing.T) {
+	var h workerHealth
+	h.register("publisher", time.Minute)
+	h.register("listener", 0)
+
+	for range workerFailureThreshold - 1 {
+		h.report("publisher", errors.New("connection refused"))
+	}
+	if c := h.checks(time.Now())["worker:publisher"]; c.Status != checkOK {
+		t.Fatalf("worker failed after %d errors: %+v", workerFailureThreshold-1, c)
+	}
+
+	h.report("publisher", errors.New("connection refused"))
+	if c := h.checks(time.Now())["worker:publisher"]; c.Status != checkFail || !strings.Contains(c.Detail, "connection refused") {
+		t.Fatalf("worker did not fail after %d errors: %+v", workerFailureThreshold, c)
+	}
+
+	h.report("publisher", nil)
+	later := time.Now().Add(2 * time.Minute)
+	checks := h.checks(later)
+	if c := checks["worker:publisher"]; c.Status != checkFail {
+		t.Fatalf("stalled worker did not fail: %+v", c)
+	}
+	if c := checks["worker:listener"]; c.Status != checkOK {
+		t.Fatalf("worker without a deadline failed: %+v", c)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	// Nothing listens on port 1, so the database checks fail at once.
+	db, err := sql.Open("postgres", "postgres://chirpy@127.0.0.1:1/chirpy?sslmode=disable")
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	defer db.Close()
+
+	migrations, err := newMigrator(db)
+	if err != nil {
+		t.Fatalf("newMigrator failed: %s", err)
+	}
+
+	cfg := &apiConfig{conn: db, migrations: migrations}
+	cfg.draining.Store(true)
+
+	rec := httptest.NewRecorder()
+	cfg.handleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status is %d, expected %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	var result readiness
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("response is not JSON: %s", err)
+	}
+	if result.Status != checkFail {
+		t.Fatalf("status is %q, expected %q", result.Status, checkFail)
+	}
+	for _, name := range []string{"server", "database", "schema"} {
+		if c := result.Checks[name]; c.Status != checkFail || c.Detail == "" {
+			t.Fatalf("%s check is %+v, expected a failure with detail", name, c)
+		}
+	}
+}
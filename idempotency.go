@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/auth"
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	maxIdempotencyKeyLength  = 255
+	idempotencyTTL           = 24 * time.Hour
+	idempotencySweepInterval = time.Hour
+)
+
+// routeTimeouts lists the apiMux routes whose handlers replace the server's
+// read and write timeouts. Their request bodies are read here before the
+// handler runs, so the deadlines have to be extended first.
+var routeTimeouts = map[string]time.Duration{
+	"POST /chirps/import": importTimeout,
+}
+
+// responseRecorder passes a response through to the client while keeping a
+// copy so it can be replayed. Unwrap lets http.ResponseController reach the
+// connection.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// middlewareIdempotency makes POST and PATCH requests that carry an
+// Idempotency-Key safe to retry. The first request with a key runs normally
+// and its response is stored. A retry with the same key and the same request
+// gets the stored response back. Reusing a key for a different request, or
+// retrying while the first attempt is still running, is a conflict. Server
+// errors and rate limited responses are not stored, so those can be retried
+// with the same key.
+func (cfg *apiConfig) middlewareIdempotency(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			respondWithError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		// Only requests with a valid access token get the longer deadlines,
+		// as the handlers only extend them after authenticating.
+		_, pattern := mux.Handler(r)
+		if timeout, ok := routeTimeouts[pattern]; ok {
+			if token, err := auth.GetBearerToken(r.Header); err == nil {
+				if _, err := auth.ValidateJWT(token, cfg.tokenSecret); err == nil {
+					extendTimeouts(w, timeout)
+				}
+			}
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondWithError(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+				return
+			}
+			respondWithError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		subject := cfg.clientKey(r, true)
+		hash := requestHash(r, body)
+
+		created, err := cfg.db.CreateIdempotencyKey(r.Context(), database.CreateIdempotencyKeyParams{
+			Subject:     subject,
+			Key:         key,
+			RequestHash: hash,
+			TtlSeconds:  idempotencyTTL.Seconds(),
+		})
+		if err != nil {
+			respondWithServerError(w, r, "Failed to store Idempotency-Key", err)
+			return
+		}
+
+		if created == 0 {
+			stored, err := cfg.db.GetIdempotencyKey(r.Context(), database.GetIdempotencyKeyParams{Subject: subject, Key: key})
+			if err != nil {
+				respondWithServerError(w, r, "Failed to get Idempotency-Key", err)
+				return
+			}
+
+			if stored.RequestHash != hash {
+				respondWithError(w, http.StatusConflict, "Idempotency-Key was already used for a different request")
+				return
+			}
+
+			if !stored.CompletedAt.Valid {
+				respondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+				return
+			}
+
+			if stored.ContentType.Valid {
+				w.Header().Set("Content-Type", stored.ContentType.String)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(int(stored.ResponseStatus.Int32))
+			w.Write(stored.ResponseBody)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		// The response has already been sent, so finish up even if the client
+		// has gone away.
+		ctx := context.WithoutCancel(r.Context())
+
+		if rec.status >= 500 || rec.status == http.StatusTooManyRequests {
+			if err := cfg.db.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{Subject: subject, Key: key}); err != nil {
+				loggerFrom(ctx).Error("Error releasing idempotency key", "error", err)
+			}
+			return
+		}
+
+		contentType := rec.Header().Get("Content-Type")
+		if err := cfg.db.CompleteIdempotencyKey(ctx, database.CompleteIdempotencyKeyParams{
+			Subject:        subject,
+			Key:            key,
+			ResponseStatus: sql.NullInt32{Int32: int32(rec.status), Valid: true},
+			ContentType:    sql.NullString{String: contentType, Valid: contentType != ""},
+			ResponseBody:   rec.body.Bytes(),
+		}); err != nil {
+			loggerFrom(ctx).Error("Error storing idempotent response", "error", err)
+			if err := cfg.db.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{Subject: subject, Key: key}); err != nil {
+				loggerFrom(ctx).Error("Error releasing idempotency key", "error", err)
+			}
+		}
+	})
+}
+
+func (cfg *apiConfig) runIdempotencySweeper(ctx context.Context) {
+	cfg.runWorker(ctx, "idempotency key sweeper", idempotencySweepInterval, 1, func(ctx context.Context) (int, error) {
+		_, err := cfg.db.DeleteExpiredIdempotencyKeys(ctx, idempotencyTTL.Seconds())
+		return 0, err
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/importer"
+)
+
+const (
+	maxImportSize = 10 << 20
+	// importTimeout replaces the server's read and write timeouts for an
+	// import, which saves every row in a transaction of its own and can take
+	// far longer than other requests.
+	importTimeout = 10 * time.Minute
+)
+
+var errImportStopped = errors.New("import stopped")
+
+// importChirps creates a chirp for every valid row read from r. Each row is
+// committed on its own, so an import that is cut short can be run again with
+// the same file: rows whose external ID was already imported are skipped.
+// Imported chirps are not new, so unlike created ones they are not streamed and
+// trigger no webhooks or mention notifications.
+// Problems with a single row are collected in the result. A database failure
+// aborts the import with an error wrapping errImportStopped; any other error
+// means the file itself could not be read.
+func (cfg *apiConfig) importChirps(ctx context.Context, userID uuid.UUID, r io.Reader, format string) (importer.Result, error) {
+	result := importer.Result{Errors: []importer.RowError{}}
+
+	err := importer.Read(r, format, func(row importer.Row, err error) error {
+		if err == nil {
+			row.Body, err = cleanChirpBody(row.Body)
+		}
+		if err == nil && row.CreatedAt.After(time.Now()) {
+			err = errors.New("created_at must not be in the future")
+		}
+
+		if err == nil {
+			imported, err := cfg.importChirp(ctx, userID, row)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errImportStopped, err)
+			}
+			if imported {
+				result.Imported++
+				cfg.metrics.chirpsCreated.WithLabelValues(chirpSourceImport).Inc()
+			} else {
+				result.Skipped++
+			}
+			return nil
+		}
+
+		result.Errors = append(result.Errors, importer.RowError{Line: row.Line, ExternalID: row.ExternalID, Error: err.Error()})
+		return nil
+	})
+
+	return result, err
+}
+
+func (cfg *apiConfig) importChirp(ctx context.Context, userID uuid.UUID, row importer.Row) (bool, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTracedTx(tx)
+
+	claimed, err := qtx.ClaimChirpImport(ctx, database.ClaimChirpImportParams{UserID: userID, ExternalID: row.ExternalID})
+	if err != nil {
+		return false, err
+	}
+	if claimed == 0 {
+		return false, nil
+	}
+
+	// Keeps the chirp_published trigger from announcing the chirp.
+	if err := qtx.MarkChirpImport(ctx); err != nil {
+		return false, err
+	}
+
+	chirp, err := qtx.CreateImportedChirp(ctx, database.CreateImportedChirpParams{
+		CreatedAt: row.CreatedAt,
+		Body:      row.Body,
+		UserID:    userID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := qtx.SetChirpImportChirp(ctx, database.SetChirpImportChirpParams{
+		UserID:     userID,
+		ExternalID: row.ExternalID,
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	}); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// runImportCommand implements `chirpy import`, which imports a file of chirps
+// for a user directly against the database.
+func runImportCommand(cfg *apiConfig, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	user := fs.String("user", "", "email or ID of the user the chirps belong to")
+	format := fs.String("format", "", "file format, jsonl or csv (defaults to the file extension)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: chirpy import -user <email|id> [-format jsonl|csv] <file>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *user == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	fileFormat, err := importer.DetectFormat(*format, "", path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+
+	var dbUser database.User
+	if id, parseErr := uuid.Parse(*user); parseErr == nil {
+		dbUser, err = cfg.db.GetUserByID(ctx, id)
+	} else {
+		dbUser, err = cfg.db.GetUserByEmail(ctx, *user)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find user %s: %s\n", *user, err)
+		return 1
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	result, err := cfg.importChirps(ctx, dbUser.ID, f, fileFormat)
+	for _, rowErr := range result.Errors {
+		fmt.Fprintf(os.Stderr, "line %d (%s): %s\n", rowErr.Line, rowErr.ExternalID, rowErr.Error)
+	}
+	fmt.Printf("Imported %d chirps, skipped %d already imported, %d failed\n", result.Imported, result.Skipped, len(result.Errors))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(result.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,1125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/auth"
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/dbtest"
+	"github.com/debobrad579/chirpy/internal/importer"
+	"github.com/debobrad579/chirpy/internal/notifications"
+	"github.com/debobrad579/chirpy/internal/ratelimit"
+	"github.com/debobrad579/chirpy/internal/stream"
+	"github.com/debobrad579/chirpy/internal/webhooks"
+)
+
+const (
+	testTokenSecret = "0123456789abcdefghijklmnopqrstuv"
+	testPolkaKey    = "test-polka-key"
+	testAdminKey    = "test-admin-key"
+)
+
+// noRateLimits lets every request through. The suite signs up and logs in
+// far more often than the limits allow from a single address.
+type noRateLimits struct{}
+
+func (noRateLimits) Take(_ context.Context, _ string, policy ratelimit.Policy, _ time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{Allowed: true, Remaining: policy.Limit}, nil
+}
+
+func (noRateLimits) Sweep(context.Context, time.Time) (int, error) {
+	return 0, nil
+}
+
+// testServer serves the whole application from a migrated throwaway
+// database and remembers which routes the test has requested.
+type testServer struct {
+	cfg *apiConfig
+	srv *httptest.Server
+
+	// muxes routes requests the same way the server does, to find the
+	// route a request is for.
+	muxes map[string]*http.ServeMux
+
+	mu      sync.Mutex
+	covered map[string]bool
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	schema, err := fs.Sub(migrationFiles, "sql/schema")
+	if err != nil {
+		t.Fatalf("fs.Sub failed: %s", err)
+	}
+	db := dbtest.New(t, schema)
+
+	migrations, err := newMigrator(db)
+	if err != nil {
+		t.Fatalf("newMigrator failed: %s", err)
+	}
+
+	queries := database.New(db)
+	cfg := &apiConfig{
+		db:            *queries,
+		conn:          db,
+		platform:      "dev",
+		tokenSecret:   testTokenSecret,
+		polkaKey:      testPolkaKey,
+		adminKey:      testAdminKey,
+		chirpStream:   stream.NewBroker(chirpReplaySize),
+		notifications: notifications.NewHub(),
+		rateLimits:    noRateLimits{},
+		metrics:       newMetrics(db),
+		migrations:    migrations,
+		webhookClient: webhooks.NewClient(deliveryTimeout),
+		store:         queries,
+		shutdown:      make(chan struct{}),
+
+		deactivationGracePeriod: 30 * 24 * time.Hour,
+		deletionPolicy:          deletionPolicyAnonymize,
+	}
+
+	s := &testServer{
+		cfg: cfg,
+		srv: httptest.NewServer(cfg.middlewareRequestLog(cfg.routes())),
+		muxes: map[string]*http.ServeMux{
+			"/api/v1": apiMux(cfg),
+			"/admin":  adminMux(cfg),
+		},
+		covered: map[string]bool{},
+	}
+	t.Cleanup(s.srv.Close)
+	// Streaming handlers only return once the server shuts down.
+	t.Cleanup(func() { close(cfg.shutdown) })
+	return s
+}
+
+// cover records the route that a request for method and path is served by.
+func (s *testServer) cover(method, path string) {
+	for prefix, mux := range s.muxes {
+		rest, ok := strings.CutPrefix(path, prefix)
+		if !ok || !strings.HasPrefix(rest, "/") {
+			continue
+		}
+		rest, _, _ = strings.Cut(rest, "?")
+		if _, pattern := mux.Handler(httptest.NewRequest(method, rest, nil)); pattern != "" {
+			s.mu.Lock()
+			s.covered[mountedRoute(prefix, pattern)] = true
+			s.mu.Unlock()
+		}
+	}
+}
+
+type response struct {
+	request string
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// do sends a request with the given Authorization header, which may be
+// empty. A string body is sent as it is and anything else as JSON.
+func (s *testServer) do(t *testing.T, method, path, authorization string, body any) response {
+	t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("failed to encode request body: %s", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.srv.URL+path, r)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %s", err)
+	}
+	if _, ok := body.(string); !ok && body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	s.cover(method, path)
+
+	res, err := s.srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %s", method, path, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read response to %s %s: %s", method, path, err)
+	}
+	return response{request: method + " " + path, status: res.StatusCode, header: res.Header, body: data}
+}
+
+func (res response) expect(t *testing.T, status int) response {
+	t.Helper()
+
+	if res.status != status {
+		t.Fatalf("%s responded %d, expected %d: %s", res.request, res.status, status, res.body)
+	}
+	return res
+}
+
+func (res response) decode(t *testing.T, dst any) {
+	t.Helper()
+
+	if err := json.Unmarshal(res.body, dst); err != nil {
+		t.Fatalf("response to %s is not the expected JSON: %s\n%s", res.request, err, res.body)
+	}
+}
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+func apiKey(key string) string {
+	return "ApiKey " + key
+}
+
+type testUser struct {
+	ID           uuid.UUID
+	Email        string
+	password     string
+	token        string
+	refreshToken string
+}
+
+func (u *testUser) auth() string {
+	return bearer(u.token)
+}
+
+// signUp creates a user with a unique email and logs them in.
+func (s *testServer) signUp(t *testing.T) *testUser {
+	t.Helper()
+
+	u := &testUser{Email: "user-" + uuid.NewString()[:8] + "@example.com", password: "correct horse battery staple"}
+	var created database.CreateUserRow
+	s.do(t, "POST", "/api/v1/users", "", map[string]string{"email": u.Email, "password": u.password}).
+		expect(t, http.StatusCreated).decode(t, &created)
+	u.ID = created.ID
+	s.login(t, u)
+	return u
+}
+
+func (s *testServer) login(t *testing.T, u *testUser) {
+	t.Helper()
+
+	var login struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": u.Email, "password": u.password}).
+		expect(t, http.StatusOK).decode(t, &login)
+	u.token, u.refreshToken = login.Token, login.RefreshToken
+}
+
+func (s *testServer) chirp(t *testing.T, u *testUser, body string) chirpResponse {
+	t.Helper()
+
+	var chirp chirpResponse
+	s.do(t, "POST", "/api/v1/chirps", u.auth(), map[string]string{"body": body}).
+		expect(t, http.StatusCreated).decode(t, &chirp)
+	return chirp
+}
+
+func TestAPI(t *testing.T) {
+	s := newTestServer(t)
+
+	t.Run("probes", func(t *testing.T) {
+		s.do(t, "GET", "/livez", "", nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/readyz", "", nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/metrics", "", nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/api/v1/healthz", "", nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/api/healthz", "", nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/api/v1/openapi.json", "", nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/admin/metrics", "", nil).expect(t, http.StatusOK)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		s.do(t, "POST", "/api/v1/users", "", map[string]string{"email": "not an email", "password": ""}).
+			expect(t, http.StatusBadRequest)
+
+		u := s.signUp(t)
+		if u.token == "" || u.refreshToken == "" {
+			t.Fatal("login did not return both tokens")
+		}
+
+		s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": u.Email, "password": "wrong"}).
+			expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": "nobody@example.com", "password": "wrong"}).
+			expect(t, http.StatusUnauthorized)
+
+		update := map[string]string{"email": "renamed-" + u.Email, "password": "new password"}
+		s.do(t, "PUT", "/api/v1/users", "", update).expect(t, http.StatusUnauthorized)
+
+		forged, err := auth.MakeJWT(u.ID, "some other secret that is long enough", time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT failed: %s", err)
+		}
+		s.do(t, "PUT", "/api/v1/users", bearer(forged), update).expect(t, http.StatusUnauthorized)
+
+		expired, err := auth.MakeJWT(u.ID, testTokenSecret, -time.Minute)
+		if err != nil {
+			t.Fatalf("MakeJWT failed: %s", err)
+		}
+		s.do(t, "PUT", "/api/v1/users", bearer(expired), update).expect(t, http.StatusUnauthorized)
+
+		var updated database.UpdateUserRow
+		s.do(t, "PUT", "/api/v1/users", u.auth(), update).expect(t, http.StatusOK).decode(t, &updated)
+		if updated.Email != update["email"] {
+			t.Fatalf("email is %q after the update, expected %q", updated.Email, update["email"])
+		}
+		u.Email, u.password = update["email"], update["password"]
+		s.login(t, u)
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		u := s.signUp(t)
+
+		s.do(t, "POST", "/api/v1/refresh", "", nil).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/refresh", bearer("not-a-refresh-token"), nil).expect(t, http.StatusUnauthorized)
+
+		var refreshed struct {
+			Token string `json:"token"`
+		}
+		s.do(t, "POST", "/api/v1/refresh", bearer(u.refreshToken), nil).expect(t, http.StatusOK).decode(t, &refreshed)
+		s.do(t, "GET", "/api/v1/blocks", bearer(refreshed.Token), nil).expect(t, http.StatusOK)
+
+		s.do(t, "POST", "/api/v1/revoke", "", nil).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/revoke", bearer("not-a-refresh-token"), nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/revoke", bearer(u.refreshToken), nil).expect(t, http.StatusNoContent)
+		s.do(t, "POST", "/api/v1/refresh", bearer(u.refreshToken), nil).expect(t, http.StatusUnauthorized)
+	})
+
+	t.Run("chirps", func(t *testing.T) {
+		alice, bob := s.signUp(t), s.signUp(t)
+
+		s.do(t, "POST", "/api/v1/chirps", "", map[string]string{"body": "Hello"}).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/chirps", alice.auth(), map[string]string{"body": strings.Repeat("a", 141)}).
+			expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/chirps", alice.auth(), map[string]string{"body": " "}).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/chirps", alice.auth(), map[string]string{"body": "Hello", "extra": "field"}).
+			expect(t, http.StatusBadRequest)
+
+		chirp := s.chirp(t, alice, "What a kerfuffle")
+		if chirp.Body != "What a ****" || chirp.UserID != alice.ID {
+			t.Fatalf("created %+v", chirp)
+		}
+		// Bodies need not be unique.
+		s.chirp(t, bob, "What a kerfuffle")
+
+		var chirps []chirpResponse
+		s.do(t, "GET", "/api/v1/chirps?author_id="+alice.ID.String(), "", nil).expect(t, http.StatusOK).decode(t, &chirps)
+		if len(chirps) != 1 || chirps[0].ID != chirp.ID {
+			t.Fatalf("alice's chirps are %+v", chirps)
+		}
+		s.do(t, "GET", "/api/v1/chirps?sort=desc", "", nil).expect(t, http.StatusOK).decode(t, &chirps)
+		if len(chirps) < 2 || chirps[0].CreatedAt.Before(chirps[len(chirps)-1].CreatedAt) {
+			t.Fatalf("chirps are not sorted newest first: %+v", chirps)
+		}
+		s.do(t, "GET", "/api/v1/chirps?sort=sideways", "", nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", "/api/v1/chirps?author_id=alice", "", nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", "/api/v1/chirps", bearer("garbage"), nil).expect(t, http.StatusUnauthorized)
+
+		var got chirpResponse
+		s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), "", nil).expect(t, http.StatusOK).decode(t, &got)
+		if got.ID != chirp.ID {
+			t.Fatalf("got chirp %s, expected %s", got.ID, chirp.ID)
+		}
+		s.do(t, "GET", "/api/v1/chirps/123", "", nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", "/api/v1/chirps/"+uuid.NewString(), "", nil).expect(t, http.StatusNotFound)
+
+		s.do(t, "DELETE", "/api/v1/chirps/"+chirp.ID.String(), "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "DELETE", "/api/v1/chirps/"+chirp.ID.String(), bob.auth(), nil).expect(t, http.StatusForbidden)
+		s.do(t, "DELETE", "/api/v1/chirps/"+chirp.ID.String(), alice.auth(), nil).expect(t, http.StatusNoContent)
+		s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), "", nil).expect(t, http.StatusNotFound)
+	})
+
+	t.Run("idempotency", func(t *testing.T) {
+		u := s.signUp(t)
+
+		send := func(body string) response {
+			req, err := http.NewRequest("POST", s.srv.URL+"/api/v1/chirps", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("NewRequest failed: %s", err)
+			}
+			req.Header.Set("Authorization", u.auth())
+			req.Header.Set(idempotencyKeyHeader, "retry-me")
+			res, err := s.srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("POST /api/v1/chirps failed: %s", err)
+			}
+			defer res.Body.Close()
+			data, _ := io.ReadAll(res.Body)
+			return response{request: "POST /api/v1/chirps", status: res.StatusCode, header: res.Header, body: data}
+		}
+
+		first := send(`{"body":"Exactly once"}`).expect(t, http.StatusCreated)
+		retry := send(`{"body":"Exactly once"}`).expect(t, http.StatusCreated)
+		if !bytes.Equal(first.body, retry.body) || retry.header.Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("retry was not replayed: %s", retry.body)
+		}
+		send(`{"body":"Something else"}`).expect(t, http.StatusConflict)
+	})
+
+	t.Run("scheduled chirps", func(t *testing.T) {
+		alice, bob := s.signUp(t), s.signUp(t)
+
+		past := time.Now().Add(-time.Hour)
+		s.do(t, "POST", "/api/v1/chirps", alice.auth(), map[string]any{"body": "Too late", "publish_at": past}).
+			expect(t, http.StatusBadRequest)
+
+		var chirp chirpResponse
+		s.do(t, "POST", "/api/v1/chirps", alice.auth(), map[string]any{"body": "Later", "publish_at": time.Now().Add(time.Hour)}).
+			expect(t, http.StatusCreated).decode(t, &chirp)
+		if chirp.IsPublished {
+			t.Fatal("scheduled chirp was published at once")
+		}
+
+		var scheduled []chirpResponse
+		s.do(t, "GET", "/api/v1/chirps/scheduled", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/api/v1/chirps/scheduled", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &scheduled)
+		if len(scheduled) != 1 || scheduled[0].ID != chirp.ID {
+			t.Fatalf("scheduled chirps are %+v", scheduled)
+		}
+
+		path := "/api/v1/chirps/scheduled/" + chirp.ID.String()
+		s.do(t, "PUT", path, bob.auth(), map[string]string{"body": "Mine now"}).expect(t, http.StatusForbidden)
+		s.do(t, "PUT", path, "", map[string]string{"body": "Edited"}).expect(t, http.StatusUnauthorized)
+		s.do(t, "PUT", "/api/v1/chirps/scheduled/"+uuid.NewString(), alice.auth(), map[string]string{"body": "Edited"}).
+			expect(t, http.StatusNotFound)
+
+		var edited chirpResponse
+		s.do(t, "PUT", path, alice.auth(), map[string]string{"body": "Edited"}).expect(t, http.StatusOK).decode(t, &edited)
+		if edited.Body != "Edited" || edited.PublishAt == nil || !edited.PublishAt.Equal(*chirp.PublishAt) {
+			t.Fatalf("edited chirp is %+v", edited)
+		}
+
+		s.do(t, "DELETE", path, bob.auth(), nil).expect(t, http.StatusForbidden)
+		s.do(t, "DELETE", path, alice.auth(), nil).expect(t, http.StatusNoContent)
+		s.do(t, "DELETE", path, alice.auth(), nil).expect(t, http.StatusNotFound)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		u := s.signUp(t)
+
+		file := fmt.Sprintf(`{"external_id":"1","body":"First","created_at":"2020-01-01T00:00:00Z"}
+{"external_id":"2","body":%q,"created_at":"2020-01-02T00:00:00Z"}
+{"external_id":"3","body":"","created_at":"2020-01-03T00:00:00Z"}
+`, strings.Repeat("a", 141))
+		s.do(t, "POST", "/api/v1/chirps/import?format=jsonl", "", file).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/chirps/import", u.auth(), file).expect(t, http.StatusBadRequest)
+
+		var result importer.Result
+		s.do(t, "POST", "/api/v1/chirps/import?format=jsonl", u.auth(), file).expect(t, http.StatusOK).decode(t, &result)
+		if result.Imported != 1 || len(result.Errors) != 2 || result.Errors[0].Line != 2 || result.Errors[1].Line != 3 {
+			t.Fatalf("first import gave %+v", result)
+		}
+
+		s.do(t, "POST", "/api/v1/chirps/import?format=jsonl", u.auth(), file).expect(t, http.StatusOK).decode(t, &result)
+		if result.Imported != 0 || result.Skipped != 1 {
+			t.Fatalf("second import gave %+v, expected the row to be skipped", result)
+		}
+	})
+
+	t.Run("blocks and mutes", func(t *testing.T) {
+		alice, bob := s.signUp(t), s.signUp(t)
+		chirp := s.chirp(t, alice, "Not for bob")
+
+		for _, kind := range []string{"block", "mute"} {
+			path := "/api/v1/users/" + bob.ID.String() + "/" + kind
+			s.do(t, "POST", path, "", nil).expect(t, http.StatusUnauthorized)
+			s.do(t, "POST", "/api/v1/users/"+alice.ID.String()+"/"+kind, alice.auth(), nil).expect(t, http.StatusBadRequest)
+			s.do(t, "POST", "/api/v1/users/"+uuid.NewString()+"/"+kind, alice.auth(), nil).expect(t, http.StatusNotFound)
+			s.do(t, "POST", "/api/v1/users/bob/"+kind, alice.auth(), nil).expect(t, http.StatusBadRequest)
+			s.do(t, "POST", path, alice.auth(), nil).expect(t, http.StatusNoContent)
+		}
+
+		var blocks []database.Block
+		s.do(t, "GET", "/api/v1/blocks", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/api/v1/blocks", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &blocks)
+		if len(blocks) != 1 || blocks[0].BlockedID != bob.ID {
+			t.Fatalf("blocks are %+v", blocks)
+		}
+
+		var mutes []database.Mute
+		s.do(t, "GET", "/api/v1/mutes", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/api/v1/mutes", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &mutes)
+		if len(mutes) != 1 || mutes[0].MutedID != bob.ID {
+			t.Fatalf("mutes are %+v", mutes)
+		}
+
+		// Alice's chirps are hidden from bob while she blocks him.
+		s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), bob.auth(), nil).expect(t, http.StatusNotFound)
+
+		for _, kind := range []string{"block", "mute"} {
+			path := "/api/v1/users/" + bob.ID.String() + "/" + kind
+			s.do(t, "DELETE", path, "", nil).expect(t, http.StatusUnauthorized)
+			s.do(t, "DELETE", path, alice.auth(), nil).expect(t, http.StatusNoContent)
+		}
+
+		s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), bob.auth(), nil).expect(t, http.StatusOK)
+		s.do(t, "GET", "/api/v1/blocks", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &blocks)
+		s.do(t, "GET", "/api/v1/mutes", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &mutes)
+		if len(blocks) != 0 || len(mutes) != 0 {
+			t.Fatalf("blocks %+v and mutes %+v remain", blocks, mutes)
+		}
+	})
+
+	t.Run("conversations", func(t *testing.T) {
+		alice, bob, carol := s.signUp(t), s.signUp(t), s.signUp(t)
+
+		members := map[string]any{"member_ids": []uuid.UUID{bob.ID}}
+		s.do(t, "POST", "/api/v1/conversations", "", members).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/conversations", alice.auth(), map[string]any{"member_ids": []uuid.UUID{alice.ID}}).
+			expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/conversations", alice.auth(), map[string]any{"member_ids": []uuid.UUID{uuid.New()}}).
+			expect(t, http.StatusBadRequest)
+
+		var conversation, again database.Conversation
+		s.do(t, "POST", "/api/v1/conversations", alice.auth(), members).expect(t, http.StatusCreated).decode(t, &conversation)
+		s.do(t, "POST", "/api/v1/conversations", alice.auth(), members).expect(t, http.StatusOK).decode(t, &again)
+		if again.ID != conversation.ID {
+			t.Fatal("a second direct conversation was created between the same users")
+		}
+
+		var conversations []database.Conversation
+		s.do(t, "GET", "/api/v1/conversations", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/api/v1/conversations", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &conversations)
+		if len(conversations) != 1 || conversations[0].ID != conversation.ID {
+			t.Fatalf("bob's conversations are %+v", conversations)
+		}
+
+		path := "/api/v1/conversations/" + conversation.ID.String()
+		var details struct {
+			Members []conversationMemberResponse `json:"members"`
+		}
+		s.do(t, "GET", path, bob.auth(), nil).expect(t, http.StatusOK).decode(t, &details)
+		if len(details.Members) != 2 {
+			t.Fatalf("conversation has %d members, expected 2", len(details.Members))
+		}
+		s.do(t, "GET", path, "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", path, carol.auth(), nil).expect(t, http.StatusNotFound)
+		s.do(t, "GET", "/api/v1/conversations/123", bob.auth(), nil).expect(t, http.StatusBadRequest)
+
+		s.do(t, "POST", path+"/messages", "", map[string]string{"body": "Hi"}).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", path+"/messages", carol.auth(), map[string]string{"body": "Hi"}).expect(t, http.StatusNotFound)
+		s.do(t, "POST", path+"/messages", alice.auth(), map[string]string{"body": ""}).expect(t, http.StatusBadRequest)
+
+		var message database.Message
+		s.do(t, "POST", path+"/messages", alice.auth(), map[string]string{"body": "Hi bob"}).
+			expect(t, http.StatusCreated).decode(t, &message)
+
+		var messages []database.Message
+		s.do(t, "GET", path+"/messages", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", path+"/messages", carol.auth(), nil).expect(t, http.StatusNotFound)
+		s.do(t, "GET", path+"/messages?limit=0", bob.auth(), nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", path+"/messages?before=yesterday", bob.auth(), nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", path+"/messages?limit=10", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &messages)
+		if len(messages) != 1 || messages[0].ID != message.ID {
+			t.Fatalf("messages are %+v", messages)
+		}
+
+		var notes []notificationResponse
+		s.do(t, "GET", "/api/v1/notifications?unread=true", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &notes)
+		if len(notes) != 1 || notes[0].Type != notifications.TypeMessage || notes[0].ConversationID == nil || *notes[0].ConversationID != conversation.ID {
+			t.Fatalf("bob's notifications are %+v", notes)
+		}
+
+		s.do(t, "POST", path+"/read", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", path+"/read", carol.auth(), nil).expect(t, http.StatusNotFound)
+		s.do(t, "POST", path+"/read", bob.auth(), nil).expect(t, http.StatusNoContent)
+
+		s.do(t, "PUT", path+"/mute", "", map[string]bool{"muted": true}).expect(t, http.StatusUnauthorized)
+		s.do(t, "PUT", path+"/mute", carol.auth(), map[string]bool{"muted": true}).expect(t, http.StatusNotFound)
+		s.do(t, "PUT", path+"/mute", bob.auth(), map[string]bool{"muted": true}).expect(t, http.StatusNoContent)
+
+		// Bob has muted the conversation, so this message does not notify him.
+		s.do(t, "POST", path+"/messages", alice.auth(), map[string]string{"body": "Still there?"}).expect(t, http.StatusCreated)
+		s.do(t, "GET", "/api/v1/notifications?unread=true", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &notes)
+		if len(notes) != 1 {
+			t.Fatalf("bob has %d unread notifications after muting the conversation, expected 1", len(notes))
+		}
+
+		s.do(t, "POST", "/api/v1/users/"+alice.ID.String()+"/block", carol.auth(), nil).expect(t, http.StatusNoContent)
+		s.do(t, "POST", "/api/v1/conversations", alice.auth(), map[string]any{"member_ids": []uuid.UUID{carol.ID}}).
+			expect(t, http.StatusForbidden)
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		alice, bob := s.signUp(t), s.signUp(t)
+		s.chirp(t, alice, "Hello @"+bob.Email+"!")
+
+		var notes []notificationResponse
+		s.do(t, "GET", "/api/v1/notifications", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/api/v1/notifications", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &notes)
+		if len(notes) != 1 || notes[0].Type != notifications.TypeMention || notes[0].ActorID == nil || *notes[0].ActorID != alice.ID {
+			t.Fatalf("bob's notifications are %+v", notes)
+		}
+
+		path := "/api/v1/notifications/" + notes[0].ID.String() + "/read"
+		s.do(t, "POST", path, "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", path, alice.auth(), nil).expect(t, http.StatusNotFound)
+		s.do(t, "POST", "/api/v1/notifications/123/read", bob.auth(), nil).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", path, bob.auth(), nil).expect(t, http.StatusNoContent)
+
+		s.do(t, "GET", "/api/v1/notifications?unread=true", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &notes)
+		if len(notes) != 0 {
+			t.Fatalf("bob has %d unread notifications after reading the only one", len(notes))
+		}
+
+		s.chirp(t, alice, "Again, @"+bob.Email)
+		s.do(t, "POST", "/api/v1/notifications/read", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/notifications/read", bob.auth(), nil).expect(t, http.StatusNoContent)
+		s.do(t, "GET", "/api/v1/notifications?unread=true", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &notes)
+		if len(notes) != 0 {
+			t.Fatalf("bob has %d unread notifications after reading them all", len(notes))
+		}
+
+		s.do(t, "GET", "/api/v1/notifications?limit=0", bob.auth(), nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", "/api/v1/notifications?before=yesterday", bob.auth(), nil).expect(t, http.StatusBadRequest)
+		var newest, older []notificationResponse
+		s.do(t, "GET", "/api/v1/notifications?limit=1", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &newest)
+		if len(newest) != 1 {
+			t.Fatalf("first page of bob's notifications has %d, expected 1", len(newest))
+		}
+		before := newest[0].CreatedAt.UTC().Format(time.RFC3339Nano)
+		s.do(t, "GET", "/api/v1/notifications?limit=1&before="+before, bob.auth(), nil).expect(t, http.StatusOK).decode(t, &older)
+		if len(older) != 1 || older[0].ID == newest[0].ID || older[0].CreatedAt.After(newest[0].CreatedAt) {
+			t.Fatalf("second page of bob's notifications is %+v after %+v", older, newest)
+		}
+	})
+
+	t.Run("chirp stream", func(t *testing.T) {
+		u := s.signUp(t)
+
+		s.do(t, "GET", "/api/v1/stream/chirps?author_id=someone", "", nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", "/api/v1/stream/chirps", bearer("garbage"), nil).expect(t, http.StatusUnauthorized)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, "GET", s.srv.URL+"/api/v1/stream/chirps?author_id="+u.ID.String(), nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %s", err)
+		}
+		s.cover(req.Method, req.URL.RequestURI())
+		res, err := s.srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/v1/stream/chirps failed: %s", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("stream responded %d with %q", res.StatusCode, res.Header.Get("Content-Type"))
+		}
+
+		// The publisher and listener workers are not running, so publish
+		// straight to the broker.
+		chirp := database.Chirp{ID: uuid.New(), Body: "Live", UserID: u.ID, IsPublished: true}
+		s.cfg.chirpStream.Publish(chirp)
+
+		lines := bufio.NewScanner(res.Body)
+		for lines.Scan() {
+			data, ok := strings.CutPrefix(lines.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var got chirpResponse
+			if err := json.Unmarshal([]byte(data), &got); err != nil || got.ID != chirp.ID {
+				t.Fatalf("stream sent %s", data)
+			}
+			return
+		}
+		t.Fatalf("stream ended without the chirp: %v", lines.Err())
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		u := s.signUp(t)
+
+		s.do(t, "GET", "/api/v1/ws", "", nil).expect(t, http.StatusUnauthorized)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		conn, _, err := websocket.Dial(ctx, s.srv.URL+"/api/v1/ws", &websocket.DialOptions{
+			HTTPClient: s.srv.Client(),
+			HTTPHeader: http.Header{"Authorization": {u.auth()}},
+		})
+		if err != nil {
+			t.Fatalf("Dial failed: %s", err)
+		}
+		defer conn.CloseNow()
+
+		received := make(chan notificationResponse, 1)
+		go func() {
+			var n notificationResponse
+			if err := wsjson.Read(ctx, conn, &n); err == nil {
+				received <- n
+			}
+		}()
+
+		// The handler subscribes after the handshake, so keep publishing
+		// until the notification arrives.
+		sent := database.Notification{ID: uuid.New(), UserID: u.ID, Type: notifications.TypeMention}
+		for {
+			s.cfg.notifications.Publish(sent)
+			select {
+			case n := <-received:
+				if n.ID != sent.ID {
+					t.Fatalf("received notification %s, expected %s", n.ID, sent.ID)
+				}
+				conn.Close(websocket.StatusNormalClosure, "")
+				return
+			case <-ctx.Done():
+				t.Fatal("no notification was received")
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	})
+
+	t.Run("reports and moderation", func(t *testing.T) {
+		alice, bob, carol := s.signUp(t), s.signUp(t), s.signUp(t)
+		chirp := s.chirp(t, alice, "Something objectionable")
+
+		report := map[string]string{"reason": "spam", "details": "Buy now"}
+		chirpPath := "/api/v1/chirps/" + chirp.ID.String() + "/report"
+		s.do(t, "POST", chirpPath, "", report).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", chirpPath, alice.auth(), report).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", chirpPath, bob.auth(), map[string]string{"reason": "boring"}).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/chirps/"+uuid.NewString()+"/report", bob.auth(), report).expect(t, http.StatusNotFound)
+
+		var chirpReport reportResponse
+		s.do(t, "POST", chirpPath, bob.auth(), report).expect(t, http.StatusCreated).decode(t, &chirpReport)
+		if chirpReport.Status != reportOpen || chirpReport.ChirpID == nil || *chirpReport.ChirpID != chirp.ID {
+			t.Fatalf("created report %+v", chirpReport)
+		}
+
+		userPath := "/api/v1/users/" + alice.ID.String() + "/report"
+		s.do(t, "POST", userPath, "", report).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", userPath, alice.auth(), report).expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/users/"+uuid.NewString()+"/report", bob.auth(), report).expect(t, http.StatusNotFound)
+
+		var userReport, warnReport reportResponse
+		s.do(t, "POST", userPath, bob.auth(), report).expect(t, http.StatusCreated).decode(t, &userReport)
+		s.do(t, "POST", userPath, carol.auth(), report).expect(t, http.StatusCreated).decode(t, &warnReport)
+
+		// Only the admin key and moderators may see reports.
+		s.do(t, "GET", "/admin/reports", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/admin/reports", carol.auth(), nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/admin/reports?status=pending", apiKey(testAdminKey), nil).expect(t, http.StatusBadRequest)
+
+		var reports []reportResponse
+		s.do(t, "GET", "/admin/reports", apiKey(testAdminKey), nil).expect(t, http.StatusOK).decode(t, &reports)
+		for _, id := range []uuid.UUID{chirpReport.ID, userReport.ID, warnReport.ID} {
+			if !slices.ContainsFunc(reports, func(r reportResponse) bool { return r.ID == id }) {
+				t.Fatalf("open reports %+v do not include %s", reports, id)
+			}
+		}
+
+		moderatorPath := "/admin/moderators/" + carol.ID.String()
+		s.do(t, "PUT", moderatorPath, carol.auth(), map[string]bool{"is_moderator": true}).expect(t, http.StatusUnauthorized)
+		s.do(t, "PUT", "/admin/moderators/"+uuid.NewString(), apiKey(testAdminKey), map[string]bool{"is_moderator": true}).
+			expect(t, http.StatusNotFound)
+		s.do(t, "PUT", moderatorPath, apiKey(testAdminKey), map[string]bool{"is_moderator": true}).expect(t, http.StatusNoContent)
+		s.do(t, "GET", "/admin/reports", carol.auth(), nil).expect(t, http.StatusOK)
+
+		actionsPath := func(r reportResponse) string { return "/admin/reports/" + r.ID.String() + "/actions" }
+		s.do(t, "POST", actionsPath(chirpReport), bob.auth(), map[string]string{"action": actionHideChirp}).
+			expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", actionsPath(chirpReport), carol.auth(), map[string]string{"action": "ban"}).
+			expect(t, http.StatusBadRequest)
+		s.do(t, "POST", actionsPath(userReport), carol.auth(), map[string]string{"action": actionHideChirp}).
+			expect(t, http.StatusBadRequest)
+		s.do(t, "GET", "/admin/reports", carol.auth(), nil).expect(t, http.StatusOK).decode(t, &reports)
+		for _, id := range []uuid.UUID{chirpReport.ID, userReport.ID} {
+			if !slices.ContainsFunc(reports, func(r reportResponse) bool { return r.ID == id && r.Status == reportOpen }) {
+				t.Fatalf("report %s is not open after an invalid action: %+v", id, reports)
+			}
+		}
+		s.do(t, "POST", "/admin/reports/"+uuid.NewString()+"/actions", carol.auth(), map[string]string{"action": actionDismiss}).
+			expect(t, http.StatusNotFound)
+
+		var action database.ModerationAction
+		s.do(t, "POST", actionsPath(chirpReport), carol.auth(), map[string]string{"action": actionHideChirp, "note": "Spam"}).
+			expect(t, http.StatusCreated).decode(t, &action)
+		if action.ModeratorID.UUID != carol.ID || action.TargetChirpID.UUID != chirp.ID {
+			t.Fatalf("recorded action %+v", action)
+		}
+		s.do(t, "POST", actionsPath(chirpReport), carol.auth(), map[string]string{"action": actionDismiss}).
+			expect(t, http.StatusConflict)
+		s.do(t, "GET", "/api/v1/chirps/"+chirp.ID.String(), "", nil).expect(t, http.StatusUnavailableForLegalReasons)
+
+		s.do(t, "POST", actionsPath(warnReport), apiKey(testAdminKey), map[string]string{"action": actionWarnUser}).
+			expect(t, http.StatusCreated)
+		var notes []notificationResponse
+		s.do(t, "GET", "/api/v1/notifications", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &notes)
+		if !slices.ContainsFunc(notes, func(n notificationResponse) bool { return n.Type == notifications.TypeWarning }) {
+			t.Fatalf("alice was not warned: %+v", notes)
+		}
+
+		s.do(t, "POST", actionsPath(userReport), carol.auth(), map[string]string{"action": actionSuspendUser}).
+			expect(t, http.StatusCreated)
+		s.do(t, "GET", "/api/v1/blocks", alice.auth(), nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": alice.Email, "password": alice.password}).
+			expect(t, http.StatusForbidden)
+
+		var log []database.ModerationAction
+		s.do(t, "GET", "/admin/audit-log", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/admin/audit-log", carol.auth(), nil).expect(t, http.StatusOK).decode(t, &log)
+		for _, name := range []string{actionGrantModerator, actionHideChirp, actionWarnUser, actionSuspendUser} {
+			if !slices.ContainsFunc(log, func(a database.ModerationAction) bool { return a.Action == name }) {
+				t.Fatalf("audit log has no %s action: %+v", name, log)
+			}
+		}
+
+		s.do(t, "PUT", moderatorPath, apiKey(testAdminKey), map[string]bool{"is_moderator": false}).expect(t, http.StatusNoContent)
+		s.do(t, "GET", "/admin/reports", carol.auth(), nil).expect(t, http.StatusUnauthorized)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		alice, bob := s.signUp(t), s.signUp(t)
+
+		hook := map[string]any{"url": "https://example.com/hook", "events": []string{"chirp.created"}}
+		s.do(t, "POST", "/api/v1/webhooks", "", hook).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/webhooks", alice.auth(), map[string]any{"url": "ftp://example.com", "events": []string{"chirp.created"}}).
+			expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/webhooks", alice.auth(), map[string]any{"url": "https://example.com", "events": []string{"chirp.liked"}}).
+			expect(t, http.StatusBadRequest)
+		for _, url := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "http://10.0.0.1", "http://[::1]/", "http://localhost/"} {
+			s.do(t, "POST", "/api/v1/webhooks", alice.auth(), map[string]any{"url": url, "events": []string{"chirp.created"}}).
+				expect(t, http.StatusBadRequest)
+		}
+
+		var webhook webhookResponse
+		s.do(t, "POST", "/api/v1/webhooks", alice.auth(), hook).expect(t, http.StatusCreated).decode(t, &webhook)
+		if webhook.Secret == "" || webhook.UserID == nil || *webhook.UserID != alice.ID {
+			t.Fatalf("created webhook %+v", webhook)
+		}
+
+		var hooks []webhookResponse
+		s.do(t, "GET", "/api/v1/webhooks", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/api/v1/webhooks", bob.auth(), nil).expect(t, http.StatusOK).decode(t, &hooks)
+		if len(hooks) != 0 {
+			t.Fatalf("bob sees alice's webhooks: %+v", hooks)
+		}
+		s.do(t, "GET", "/api/v1/webhooks", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &hooks)
+		if len(hooks) != 1 || hooks[0].ID != webhook.ID {
+			t.Fatalf("alice's webhooks are %+v", hooks)
+		}
+
+		path := "/api/v1/webhooks/" + webhook.ID.String()
+		var deliveries []webhookDeliveryResponse
+		s.chirp(t, bob, "Not for alice's webhook")
+		s.do(t, "GET", path+"/deliveries", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &deliveries)
+		if len(deliveries) != 0 {
+			t.Fatalf("alice's webhook got bob's events: %+v", deliveries)
+		}
+
+		chirp := s.chirp(t, alice, "Delivered to alice's webhook")
+		s.do(t, "GET", path+"/deliveries", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", path+"/deliveries", bob.auth(), nil).expect(t, http.StatusForbidden)
+		s.do(t, "GET", "/api/v1/webhooks/"+uuid.NewString()+"/deliveries", alice.auth(), nil).expect(t, http.StatusNotFound)
+		s.do(t, "GET", path+"/deliveries", alice.auth(), nil).expect(t, http.StatusOK).decode(t, &deliveries)
+		if len(deliveries) != 1 || deliveries[0].Event != "chirp.created" || !bytes.Contains(deliveries[0].Payload, []byte(chirp.ID.String())) {
+			t.Fatalf("deliveries are %+v", deliveries)
+		}
+
+		s.do(t, "DELETE", path, "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "DELETE", path, bob.auth(), nil).expect(t, http.StatusForbidden)
+		s.do(t, "DELETE", path, alice.auth(), nil).expect(t, http.StatusNoContent)
+		s.do(t, "DELETE", path, alice.auth(), nil).expect(t, http.StatusNotFound)
+	})
+
+	t.Run("admin webhooks", func(t *testing.T) {
+		hook := map[string]any{"url": "https://example.com/admin", "events": []string{"user.upgraded"}}
+		s.do(t, "POST", "/admin/webhooks", "", hook).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/admin/webhooks", apiKey("wrong"), hook).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/admin/webhooks", apiKey(testAdminKey), map[string]any{"url": "example.com"}).
+			expect(t, http.StatusBadRequest)
+
+		var webhook webhookResponse
+		s.do(t, "POST", "/admin/webhooks", apiKey(testAdminKey), hook).expect(t, http.StatusCreated).decode(t, &webhook)
+		if webhook.UserID != nil {
+			t.Fatalf("admin webhook belongs to user %s", *webhook.UserID)
+		}
+
+		var hooks []webhookResponse
+		s.do(t, "GET", "/admin/webhooks", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/admin/webhooks", apiKey(testAdminKey), nil).expect(t, http.StatusOK).decode(t, &hooks)
+		if !slices.ContainsFunc(hooks, func(h webhookResponse) bool { return h.ID == webhook.ID }) {
+			t.Fatalf("admin webhooks %+v do not include %s", hooks, webhook.ID)
+		}
+
+		path := "/admin/webhooks/" + webhook.ID.String()
+		s.do(t, "GET", path+"/deliveries", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/admin/webhooks/"+uuid.NewString()+"/deliveries", apiKey(testAdminKey), nil).expect(t, http.StatusNotFound)
+		s.do(t, "GET", path+"/deliveries", apiKey(testAdminKey), nil).expect(t, http.StatusOK)
+
+		s.do(t, "DELETE", path, "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "DELETE", "/admin/webhooks/123", apiKey(testAdminKey), nil).expect(t, http.StatusBadRequest)
+		s.do(t, "DELETE", path, apiKey(testAdminKey), nil).expect(t, http.StatusNoContent)
+		s.do(t, "GET", path+"/deliveries", apiKey(testAdminKey), nil).expect(t, http.StatusNotFound)
+	})
+
+	t.Run("webhook delivery", func(t *testing.T) {
+		alice := s.signUp(t)
+
+		type request struct {
+			signature string
+			body      []byte
+		}
+		var (
+			mu       sync.Mutex
+			requests []request
+		)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, request{r.Header.Get(webhooks.SignatureHeader), body})
+			if len(requests) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer receiver.Close()
+
+		// The receiver listens on a loopback address, which the API refuses
+		// and the real client will not connect to.
+		client := s.cfg.webhookClient
+		s.cfg.webhookClient = receiver.Client()
+		defer func() { s.cfg.webhookClient = client }()
+
+		// Earlier subtests leave deliveries to example.com in the outbox.
+		if _, err := s.cfg.conn.ExecContext(t.Context(), "DELETE FROM webhook_deliveries"); err != nil {
+			t.Fatal(err)
+		}
+
+		const secret = "webhook secret"
+		webhook, err := s.cfg.db.CreateWebhook(t.Context(), database.CreateWebhookParams{
+			UserID: uuid.NullUUID{UUID: alice.ID, Valid: true},
+			Url:    receiver.URL,
+			Secret: secret,
+			Events: []string{"chirp.created"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := "/api/v1/webhooks/" + webhook.ID.String() + "/deliveries"
+		chirp := s.chirp(t, alice, "Sent to alice's receiver")
+
+		var deliveries []webhookDeliveryResponse
+		if n, err := s.cfg.deliverWebhooks(t.Context()); err != nil || n != 1 {
+			t.Fatalf("first run sent %d deliveries, %v", n, err)
+		}
+		s.do(t, "GET", path, alice.auth(), nil).expect(t, http.StatusOK).decode(t, &deliveries)
+		if len(deliveries) != 1 || deliveries[0].Status != webhooks.DeliveryPending || deliveries[0].Attempts != 1 ||
+			deliveries[0].LastStatusCode == nil || *deliveries[0].LastStatusCode != http.StatusInternalServerError {
+			t.Fatalf("after a 500 the deliveries are %+v", deliveries)
+		}
+		if !deliveries[0].NextAttemptAt.After(deliveries[0].UpdatedAt) {
+			t.Fatalf("the retry is not backed off: %+v", deliveries[0])
+		}
+		if n, err := s.cfg.deliverWebhooks(t.Context()); err != nil || n != 0 {
+			t.Fatalf("run before the retry is due sent %d deliveries, %v", n, err)
+		}
+
+		if _, err := s.cfg.conn.ExecContext(t.Context(), "UPDATE webhook_deliveries SET next_attempt_at = NOW()"); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := s.cfg.deliverWebhooks(t.Context()); err != nil || n != 1 {
+			t.Fatalf("retry sent %d deliveries, %v", n, err)
+		}
+		s.do(t, "GET", path, alice.auth(), nil).expect(t, http.StatusOK).decode(t, &deliveries)
+		if len(deliveries) != 1 || deliveries[0].Status != webhooks.DeliveryDelivered || deliveries[0].Attempts != 2 ||
+			deliveries[0].LastStatusCode == nil || *deliveries[0].LastStatusCode != http.StatusNoContent {
+			t.Fatalf("after the retry the deliveries are %+v", deliveries)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(requests) != 2 {
+			t.Fatalf("the receiver got %d requests, expected 2", len(requests))
+		}
+		for _, req := range requests {
+			if err := webhooks.Verify(secret, req.signature, req.body, time.Minute); err != nil {
+				t.Errorf("signature %q: %v", req.signature, err)
+			}
+			if !bytes.Contains(req.body, []byte(chirp.ID.String())) {
+				t.Errorf("the receiver got %s, expected chirp %s", req.body, chirp.ID)
+			}
+		}
+	})
+
+	t.Run("polka", func(t *testing.T) {
+		u := s.signUp(t)
+
+		upgrade := map[string]any{"event": "user.upgraded", "data": map[string]string{"user_id": u.ID.String()}}
+		s.do(t, "POST", "/api/v1/polka/webhooks", "", upgrade).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/polka/webhooks", apiKey("wrong"), upgrade).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/polka/webhooks", apiKey(testPolkaKey), "{").expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/polka/webhooks", apiKey(testPolkaKey), map[string]any{"event": "user.downgraded", "data": map[string]string{}}).
+			expect(t, http.StatusNoContent)
+		s.do(t, "POST", "/api/v1/polka/webhooks", apiKey(testPolkaKey), map[string]any{"event": "user.upgraded", "data": map[string]string{"user_id": "nobody"}}).
+			expect(t, http.StatusBadRequest)
+		s.do(t, "POST", "/api/v1/polka/webhooks", apiKey(testPolkaKey), map[string]any{"event": "user.upgraded", "data": map[string]string{"user_id": uuid.NewString()}}).
+			expect(t, http.StatusNotFound)
+		s.do(t, "POST", "/api/v1/polka/webhooks", apiKey(testPolkaKey), upgrade).expect(t, http.StatusNoContent)
+
+		var login struct {
+			IsChirpyRed bool `json:"is_chirpy_red"`
+		}
+		s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": u.Email, "password": u.password}).
+			expect(t, http.StatusOK).decode(t, &login)
+		if !login.IsChirpyRed {
+			t.Fatal("user was not upgraded to Chirpy Red")
+		}
+	})
+
+	t.Run("exports", func(t *testing.T) {
+		alice, bob := s.signUp(t), s.signUp(t)
+		s.chirp(t, alice, "Take this with me")
+
+		var job exportJobResponse
+		s.do(t, "POST", "/api/v1/users/me/export", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/users/me/export", alice.auth(), nil).expect(t, http.StatusAccepted).decode(t, &job)
+		if job.Status != exportPending {
+			t.Fatalf("new export is %q, expected %q", job.Status, exportPending)
+		}
+
+		path := "/api/v1/users/me/exports/" + job.ID.String()
+		s.do(t, "GET", path, "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", path, bob.auth(), nil).expect(t, http.StatusNotFound)
+		s.do(t, "GET", "/api/v1/users/me/exports/123", alice.auth(), nil).expect(t, http.StatusBadRequest)
+		s.do(t, "GET", path, alice.auth(), nil).expect(t, http.StatusOK)
+		s.do(t, "GET", path+"/download", alice.auth(), nil).expect(t, http.StatusConflict)
+
+		var adminJob exportJobResponse
+		s.do(t, "POST", "/admin/users/"+bob.ID.String()+"/export", bob.auth(), nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/admin/users/"+uuid.NewString()+"/export", apiKey(testAdminKey), nil).expect(t, http.StatusNotFound)
+		s.do(t, "POST", "/admin/users/"+bob.ID.String()+"/export", apiKey(testAdminKey), nil).
+			expect(t, http.StatusAccepted).decode(t, &adminJob)
+
+		// The exporter worker is not running, so build both archives here.
+		for range 2 {
+			if n, err := s.cfg.processExportJob(context.Background()); err != nil || n != 1 {
+				t.Fatalf("processExportJob returned %d, %v", n, err)
+			}
+		}
+
+		s.do(t, "GET", path+"/download", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", path+"/download", bob.auth(), nil).expect(t, http.StatusNotFound)
+		archive := s.do(t, "GET", path+"/download", alice.auth(), nil).expect(t, http.StatusOK)
+		if archive.header.Get("Content-Type") != "application/zip" || !bytes.HasPrefix(archive.body, []byte("PK")) {
+			t.Fatalf("download is not a ZIP archive: %q", archive.header.Get("Content-Type"))
+		}
+
+		adminPath := "/admin/exports/" + adminJob.ID.String()
+		var completed exportJobResponse
+		s.do(t, "GET", adminPath, "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", "/admin/exports/"+uuid.NewString(), apiKey(testAdminKey), nil).expect(t, http.StatusNotFound)
+		s.do(t, "GET", adminPath, apiKey(testAdminKey), nil).expect(t, http.StatusOK).decode(t, &completed)
+		if completed.Status != exportCompleted {
+			t.Fatalf("export is %q after processing, expected %q", completed.Status, exportCompleted)
+		}
+		s.do(t, "GET", adminPath+"/download", "", nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "GET", adminPath+"/download", apiKey(testAdminKey), nil).expect(t, http.StatusOK)
+
+		// Failed jobs expire like completed ones and are swept with them.
+		var failed exportJobResponse
+		s.do(t, "POST", "/api/v1/users/me/export", alice.auth(), nil).expect(t, http.StatusAccepted).decode(t, &failed)
+		if err := s.cfg.db.FailExportJob(context.Background(), database.FailExportJobParams{
+			ID:        failed.ID,
+			Error:     sql.NullString{String: "broken", Valid: true},
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		}); err != nil {
+			t.Fatalf("FailExportJob failed: %s", err)
+		}
+		if err := s.cfg.db.DeleteExpiredExportJobs(context.Background()); err != nil {
+			t.Fatalf("DeleteExpiredExportJobs failed: %s", err)
+		}
+		s.do(t, "GET", "/api/v1/users/me/exports/"+failed.ID.String(), alice.auth(), nil).expect(t, http.StatusNotFound)
+	})
+
+	t.Run("deactivation", func(t *testing.T) {
+		u := s.signUp(t)
+
+		s.do(t, "POST", "/api/v1/users/me/deactivate", "", map[string]string{"password": u.password}).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/users/me/deactivate", u.auth(), map[string]string{"password": "wrong"}).expect(t, http.StatusUnauthorized)
+
+		var deactivated struct {
+			DeleteAfter time.Time `json:"delete_after"`
+		}
+		s.do(t, "POST", "/api/v1/users/me/deactivate", u.auth(), map[string]string{"password": u.password}).
+			expect(t, http.StatusOK).decode(t, &deactivated)
+		if !deactivated.DeleteAfter.After(time.Now()) {
+			t.Fatalf("account is deleted after %s, expected a time in the future", deactivated.DeleteAfter)
+		}
+
+		s.do(t, "GET", "/api/v1/blocks", u.auth(), nil).expect(t, http.StatusUnauthorized)
+		s.do(t, "POST", "/api/v1/refresh", bearer(u.refreshToken), nil).expect(t, http.StatusUnauthorized)
+
+		// Logging in again reactivates the account.
+		s.login(t, u)
+		s.do(t, "GET", "/api/v1/blocks", u.auth(), nil).expect(t, http.StatusOK)
+	})
+
+	// Reset deletes every user, so it runs last.
+	t.Run("reset", func(t *testing.T) {
+		u := s.signUp(t)
+
+		s.cfg.platform = "production"
+		res := s.do(t, "POST", "/admin/reset", "", nil).expect(t, http.StatusForbidden)
+		if ct := res.header.Get("Content-Type"); ct != "application/problem+json" {
+			t.Fatalf("forbidden reset responded with %q, expected a problem", ct)
+		}
+		s.cfg.platform = "dev"
+		s.do(t, "POST", "/admin/reset", "", nil).expect(t, http.StatusOK)
+
+		s.do(t, "POST", "/api/v1/login", "", map[string]string{"email": u.Email, "password": u.password}).
+			expect(t, http.StatusUnauthorized)
+	})
+
+	// Every route should have been requested at least once, so a new route
+	// cannot be added without adding it here too.
+	if t.Failed() {
+		return
+	}
+	for _, mux := range []struct{ prefix, file, funcName string }{
+		{"/api/v1", "api.go", "apiMux"},
+		{"/admin", "admin.go", "adminMux"},
+	} {
+		for _, pattern := range registeredRoutes(t, mux.file, mux.funcName) {
+			if route := mountedRoute(mux.prefix, pattern); !s.covered[route] {
+				t.Errorf("TestAPI never requests %s", route)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -13,6 +14,22 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	StatusActive      = "active"
+	StatusSuspended   = "suspended"
+	StatusDeactivated = "deactivated"
+	StatusDeleted     = "deleted"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrInactiveUser = errors.New("user is not active")
+)
+
+type UserStatusGetter interface {
+	GetUserStatus(ctx context.Context, id uuid.UUID) (string, error)
+}
+
 func HashPassword(password string) (string, error) {
 	return argon2id.CreateHash(password, argon2id.DefaultParams)
 }
@@ -67,17 +84,26 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(token), nil
 }
 
-func AuthenticateUser(headers http.Header, secret string) (uuid.UUID, error) {
+func AuthenticateUser(ctx context.Context, headers http.Header, secret string, users UserStatusGetter) (uuid.UUID, error) {
 	token, err := GetBearerToken(headers)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, ErrInvalidToken
 	}
 
 	userID, err := ValidateJWT(token, secret)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	status, err := users.GetUserStatus(ctx, userID)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
+	if status != StatusActive {
+		return uuid.Nil, ErrInactiveUser
+	}
+
 	return userID, nil
 }
 
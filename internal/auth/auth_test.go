@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
 	"net/http"
 	"testing"
 	"time"
@@ -87,3 +89,46 @@ func TestGetBearerToken(t *testing.T) {
 		t.Fatalf("GetBearerToken returned wrong token; expected token %s, got %s", token, "mytoken")
 	}
 }
+
+type fakeUsers map[uuid.UUID]string
+
+func (f fakeUsers) GetUserStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	status, ok := f[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return status, nil
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	secret := "secret"
+	activeID := uuid.New()
+	suspendedID := uuid.New()
+	users := fakeUsers{activeID: StatusActive, suspendedID: StatusSuspended}
+
+	bearer := func(userID uuid.UUID) http.Header {
+		token, _ := MakeJWT(userID, secret, time.Minute)
+		return http.Header{"Authorization": []string{"Bearer " + token}}
+	}
+
+	userID, err := AuthenticateUser(context.Background(), bearer(activeID), secret, users)
+	if err != nil || userID != activeID {
+		t.Fatalf("AuthenticateUser failed for active user: %v", err)
+	}
+
+	if _, err := AuthenticateUser(context.Background(), bearer(suspendedID), secret, users); err != ErrInactiveUser {
+		t.Fatalf("AuthenticateUser should return ErrInactiveUser for suspended user, got %v", err)
+	}
+
+	if _, err := AuthenticateUser(context.Background(), bearer(uuid.New()), secret, users); err == nil {
+		t.Fatal("AuthenticateUser should fail for unknown user")
+	}
+
+	if _, err := AuthenticateUser(context.Background(), http.Header{}, secret, users); err != ErrInvalidToken {
+		t.Fatalf("AuthenticateUser should return ErrInvalidToken without authorization header, got %v", err)
+	}
+
+	if _, err := AuthenticateUser(context.Background(), bearer(activeID), "wrong secret", users); err != ErrInvalidToken {
+		t.Fatalf("AuthenticateUser should return ErrInvalidToken for a token signed with another secret, got %v", err)
+	}
+}
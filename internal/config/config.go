@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting the server reads at startup. Each field is
+// named by its config tag: the same name is used as the key in a config
+// file, upper-cased as an environment variable, and with dashes as a flag,
+// so token_secret is TOKEN_SECRET and -token-secret.
+type Config struct {
+	DBURL       string `config:"db_url" required:"true" redact:"url" usage:"PostgreSQL connection string, sqlite:path for a SQLite database file, or memory: to keep everything in memory"`
+	Platform    string `config:"platform" default:"prod" oneof:"dev,prod" usage:"dev enables POST /admin/reset"`
+	TokenSecret string `config:"token_secret" required:"true" redact:"secret" minlen:"32" usage:"secret used to sign access tokens"`
+	PolkaKey    string `config:"polka_key" redact:"secret" usage:"API key Polka sends with its webhooks"`
+	AdminKey    string `config:"admin_key" redact:"secret" minlen:"16" usage:"API key for the admin API, which is disabled when unset"`
+
+	DeactivationGracePeriod time.Duration `config:"deactivation_grace_period" default:"720h" usage:"time before a deactivated account is deleted"`
+	DeletedUserChirps       string        `config:"deleted_user_chirps" default:"anonymize" oneof:"anonymize,remove" usage:"what happens to the chirps of deleted users"`
+	RateLimitStore          string        `config:"rate_limit_store" default:"memory" oneof:"memory,postgres" usage:"where rate limit buckets are kept"`
+	TrustProxy              bool          `config:"trust_proxy" usage:"rate limit by the last X-Forwarded-For entry when behind one proxy"`
+	LogLevel                string        `config:"log_level" default:"info" oneof:"debug,info,warn,error" usage:"least severe level of log message written"`
+	MigrateOnStart          bool          `config:"migrate_on_start" usage:"apply pending migrations at startup, holding a lock so only one instance migrates"`
+	TraceExporter           string        `config:"trace_exporter" default:"none" oneof:"none,otlp,stdout" usage:"where to send traces; otlp is configured by OTEL_EXPORTER_OTLP_*"`
+
+	Port              int           `config:"port" default:"8080" usage:"port to listen on"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" default:"5s" usage:"time allowed to read request headers"`
+	ReadTimeout       time.Duration `config:"read_timeout" default:"30s" usage:"time allowed to read a whole request"`
+	WriteTimeout      time.Duration `config:"write_timeout" default:"1m" usage:"time allowed to write a response, except for streams"`
+	IdleTimeout       time.Duration `config:"idle_timeout" default:"2m" usage:"time an idle keep-alive connection is kept open"`
+	ShutdownDelay     time.Duration `config:"shutdown_delay" default:"0s" usage:"time to keep serving with /readyz failing before shutting down"`
+	ShutdownTimeout   time.Duration `config:"shutdown_timeout" default:"30s" usage:"time to wait for requests to finish on shutdown"`
+	TLSCertFile       string        `config:"tls_cert_file" usage:"certificate file; serves HTTPS when set with tls_key_file"`
+	TLSKeyFile        string        `config:"tls_key_file" usage:"private key file for tls_cert_file"`
+}
+
+// minSecretChars is how many different characters a secret must use, which
+// catches placeholders such as a repeated letter.
+const minSecretChars = 8
+
+type field struct {
+	name  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+func (f field) env() string {
+	return strings.ToUpper(f.name)
+}
+
+func (f field) flag() string {
+	return strings.ReplaceAll(f.name, "_", "-")
+}
+
+func (f field) set(s string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		f.value.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		f.value.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", s)
+		}
+		f.value.SetInt(int64(d))
+	}
+	return nil
+}
+
+func (c *Config) fields() []field {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	fields := make([]field, t.NumField())
+	for i := range fields {
+		fields[i] = field{name: t.Field(i).Tag.Get("config"), value: v.Field(i), tag: t.Field(i).Tag}
+	}
+	return fields
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, a config file, environment variables and flags. The file is
+// given by -config or CONFIG_FILE and may be YAML or TOML. Load returns the
+// arguments left after the flags, and fails if any setting is invalid.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	c := &Config{}
+	fields := c.fields()
+
+	fs := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	configFile := fs.String("config", "", "YAML or TOML config file (env CONFIG_FILE)")
+	flagValues := map[string]string{}
+	for _, f := range fields {
+		usage := fmt.Sprintf("%s (env %s)", f.tag.Get("usage"), f.env())
+		record := func(s string) error {
+			flagValues[f.name] = s
+			return nil
+		}
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(f.flag(), usage, record)
+		} else {
+			fs.Func(f.flag(), usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	for _, f := range fields {
+		if def, ok := f.tag.Lookup("default"); ok {
+			if err := f.set(def); err != nil {
+				panic(fmt.Sprintf("bad default for %s: %s", f.name, err))
+			}
+		}
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		if err := c.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if s, ok := lookupEnv(f.env()); ok && s != "" {
+			if err := f.set(s); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env(), err))
+			}
+		}
+		if s, ok := flagValues[f.name]; ok {
+			if err := f.set(s); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.flag(), err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return c, fs.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string]any{}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	var errs []error
+	for _, f := range c.fields() {
+		value, ok := values[f.name]
+		if !ok {
+			continue
+		}
+		delete(values, f.name)
+
+		switch value.(type) {
+		case map[string]any, []any:
+			errs = append(errs, fmt.Errorf("%s: %s must be a single value", path, f.name))
+			continue
+		}
+		if err := f.set(fmt.Sprint(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, f.name, err))
+		}
+	}
+	for key := range values {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %s", path, key))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks every setting and reports all of the problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(f field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s (%s) %s", f.name, f.env(), fmt.Sprintf(format, args...)))
+	}
+
+	for _, f := range c.fields() {
+		switch v := f.value.Interface().(type) {
+		case string:
+			if v == "" {
+				if f.tag.Get("required") == "true" {
+					fail(f, "is required")
+				}
+				continue
+			}
+			if oneof, ok := f.tag.Lookup("oneof"); ok && !slices.Contains(strings.Split(oneof, ","), v) {
+				fail(f, "must be one of %s, not %q", strings.ReplaceAll(oneof, ",", ", "), v)
+			}
+			if minlen, ok := f.tag.Lookup("minlen"); ok {
+				n, _ := strconv.Atoi(minlen)
+				if len(v) < n {
+					fail(f, "must be at least %d characters", n)
+				} else if distinctChars(v) < minSecretChars {
+					fail(f, "must not be a repeated pattern")
+				}
+			}
+		case time.Duration:
+			if v < 0 {
+				fail(f, "must not be negative")
+			}
+		}
+	}
+
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port (PORT) must be between 1 and 65535, not %d", c.Port))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file (TLS_CERT_FILE) and tls_key_file (TLS_KEY_FILE) must be set together"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func distinctChars(s string) int {
+	seen := map[rune]bool{}
+	for _, r := range s {
+		seen[r] = true
+	}
+	return len(seen)
+}
+
+// redacted formats the value of f, hiding secrets and the password in the
+// database URL.
+func (f field) redacted() string {
+	value := fmt.Sprint(f.value.Interface())
+	switch f.tag.Get("redact") {
+	case "secret":
+		if value != "" {
+			value = "[redacted]"
+		}
+	case "url":
+		// Connection strings in key=value form are redacted whole.
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+			value = u.Redacted()
+		} else if value != "" {
+			value = "[redacted]"
+		}
+	}
+	return value
+}
+
+// String lists the effective settings one per line, with secrets redacted.
+func (c *Config) String() string {
+	var b strings.Builder
+	for _, f := range c.fields() {
+		fmt.Fprintf(&b, "%s = %s\n", f.name, f.redacted())
+	}
+	return b.String()
+}
+
+// LogValue logs the effective settings as a group, with secrets redacted.
+func (c *Config) LogValue() slog.Value {
+	fields := c.fields()
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.String(f.name, f.redacted())
+	}
+	return slog.GroupValue(attrs...)
+}
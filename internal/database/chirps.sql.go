@@ -7,24 +7,62 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, publish_at, is_published)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, $3, $4)
 RETURNING
-    id, created_at, updated_at, body, user_id
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body        string       `json:"body"`
+	UserID      uuid.UUID    `json:"user_id"`
+	PublishAt   sql.NullTime `json:"publish_at"`
+	IsPublished bool         `json:"is_published"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.PublishAt,
+		arg.IsPublished,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.PublishAt,
+		&i.IsPublished,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createImportedChirp = `-- name: CreateImportedChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, is_published)
+    VALUES (gen_random_uuid (), $1, $1, $2, $3, TRUE)
+RETURNING
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
+`
+
+type CreateImportedChirpParams struct {
+	CreatedAt time.Time `json:"created_at"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) CreateImportedChirp(ctx context.Context, arg CreateImportedChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createImportedChirp, arg.CreatedAt, arg.Body, arg.UserID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -32,6 +70,9 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.PublishAt,
+		&i.IsPublished,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -46,13 +87,71 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteScheduledChirp = `-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = $1
+    AND NOT is_published
+`
+
+func (q *Queries) DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllChirpsForUser = `-- name: GetAllChirpsForUser :many
+SELECT
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
+FROM
+    chirps
+WHERE
+    user_id = $1
+ORDER BY
+    created_at ASC
+`
+
+func (q *Queries) GetAllChirpsForUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirpsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishAt,
+			&i.IsPublished,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirp = `-- name: GetChirp :one
 SELECT
-    id, created_at, updated_at, body, user_id
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
 FROM
     chirps
 WHERE
     id = $1
+    AND is_published
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -64,26 +163,53 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.PublishAt,
+		&i.IsPublished,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
 SELECT
-    id, created_at, updated_at, body, user_id
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
 FROM
     chirps
+WHERE
+    is_published
+    AND hidden_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            blocks
+        WHERE
+            blocks.blocker_id = chirps.user_id
+            AND blocks.blocked_id = $1::uuid)
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            mutes
+        WHERE
+            mutes.muter_id = $1::uuid
+            AND mutes.muted_id = chirps.user_id)
 ORDER BY
-    CASE WHEN $1 = 'asc' THEN
+    CASE WHEN $2::text = 'asc' THEN
         created_at
     END ASC,
-    CASE WHEN $1 = 'desc' THEN
+    CASE WHEN $2::text = 'desc' THEN
         created_at
     END DESC
 `
 
-func (q *Queries) GetChirps(ctx context.Context, dollar_1 interface{}) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps, dollar_1)
+type GetChirpsParams struct {
+	ViewerID uuid.UUID `json:"viewer_id"`
+	Sort     string    `json:"sort"`
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, arg.ViewerID, arg.Sort)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +223,9 @@ func (q *Queries) GetChirps(ctx context.Context, dollar_1 interface{}) ([]Chirp,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.PublishAt,
+			&i.IsPublished,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -113,27 +242,46 @@ func (q *Queries) GetChirps(ctx context.Context, dollar_1 interface{}) ([]Chirp,
 
 const getChirpsFromAuthor = `-- name: GetChirpsFromAuthor :many
 SELECT
-    id, created_at, updated_at, body, user_id
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
 FROM
     chirps
 WHERE
     user_id = $1
+    AND is_published
+    AND hidden_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            blocks
+        WHERE
+            blocks.blocker_id = chirps.user_id
+            AND blocks.blocked_id = $2::uuid)
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            mutes
+        WHERE
+            mutes.muter_id = $2::uuid
+            AND mutes.muted_id = chirps.user_id)
 ORDER BY
-    CASE WHEN $2 = 'asc' THEN
+    CASE WHEN $3::text = 'asc' THEN
         created_at
     END ASC,
-    CASE WHEN $2 = 'desc' THEN
+    CASE WHEN $3::text = 'desc' THEN
         created_at
     END DESC
 `
 
 type GetChirpsFromAuthorParams struct {
-	UserID  uuid.UUID   `json:"user_id"`
-	Column2 interface{} `json:"column_2"`
+	UserID   uuid.UUID `json:"user_id"`
+	ViewerID uuid.UUID `json:"viewer_id"`
+	Sort     string    `json:"sort"`
 }
 
 func (q *Queries) GetChirpsFromAuthor(ctx context.Context, arg GetChirpsFromAuthorParams) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsFromAuthor, arg.UserID, arg.Column2)
+	rows, err := q.db.QueryContext(ctx, getChirpsFromAuthor, arg.UserID, arg.ViewerID, arg.Sort)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +295,9 @@ func (q *Queries) GetChirpsFromAuthor(ctx context.Context, arg GetChirpsFromAuth
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.PublishAt,
+			&i.IsPublished,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -160,3 +311,187 @@ func (q *Queries) GetChirpsFromAuthor(ctx context.Context, arg GetChirpsFromAuth
 	}
 	return items, nil
 }
+
+const getDueChirps = `-- name: GetDueChirps :many
+SELECT
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
+FROM
+    chirps
+WHERE
+    NOT is_published
+    AND publish_at <= NOW()
+ORDER BY
+    publish_at ASC
+LIMIT $1
+FOR UPDATE
+    SKIP LOCKED
+`
+
+func (q *Queries) GetDueChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDueChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishAt,
+			&i.IsPublished,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScheduledChirp = `-- name: GetScheduledChirp :one
+SELECT
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
+FROM
+    chirps
+WHERE
+    id = $1
+    AND NOT is_published
+`
+
+func (q *Queries) GetScheduledChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.PublishAt,
+		&i.IsPublished,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getScheduledChirps = `-- name: GetScheduledChirps :many
+SELECT
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
+FROM
+    chirps
+WHERE
+    user_id = $1
+    AND NOT is_published
+ORDER BY
+    publish_at ASC
+`
+
+func (q *Queries) GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishAt,
+			&i.IsPublished,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :exec
+UPDATE
+    chirps
+SET
+    hidden_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
+const publishChirp = `-- name: PublishChirp :exec
+UPDATE
+    chirps
+SET
+    is_published = TRUE,
+    created_at = publish_at,
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+func (q *Queries) PublishChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, publishChirp, id)
+	return err
+}
+
+const updateScheduledChirp = `-- name: UpdateScheduledChirp :one
+UPDATE
+    chirps
+SET
+    body = $2,
+    publish_at = $3,
+    updated_at = NOW()
+WHERE
+    id = $1
+    AND NOT is_published
+RETURNING
+    id, created_at, updated_at, body, user_id, publish_at, is_published, hidden_at
+`
+
+type UpdateScheduledChirpParams struct {
+	ID        uuid.UUID    `json:"id"`
+	Body      string       `json:"body"`
+	PublishAt sql.NullTime `json:"publish_at"`
+}
+
+func (q *Queries) UpdateScheduledChirp(ctx context.Context, arg UpdateScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledChirp, arg.ID, arg.Body, arg.PublishAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.PublishAt,
+		&i.IsPublished,
+		&i.HiddenAt,
+	)
+	return i, err
+}
@@ -6,17 +6,117 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Chirp struct {
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Body        string       `json:"body"`
+	UserID      uuid.UUID    `json:"user_id"`
+	PublishAt   sql.NullTime `json:"publish_at"`
+	IsPublished bool         `json:"is_published"`
+	HiddenAt    sql.NullTime `json:"hidden_at"`
+}
+
+type ChirpImport struct {
+	UserID     uuid.UUID     `json:"user_id"`
+	ExternalID string        `json:"external_id"`
+	ChirpID    uuid.NullUUID `json:"chirp_id"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type Conversation struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID    `json:"conversation_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	LastReadAt     sql.NullTime `json:"last_read_at"`
+	Muted          bool         `json:"muted"`
+}
+
+type ExportJob struct {
+	ID          uuid.UUID      `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	UserID      uuid.UUID      `json:"user_id"`
+	RequestedBy uuid.NullUUID  `json:"requested_by"`
+	Status      string         `json:"status"`
+	Archive     []byte         `json:"archive"`
+	Error       sql.NullString `json:"error"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	ExpiresAt   sql.NullTime   `json:"expires_at"`
+}
+
+type IdempotencyKey struct {
+	Subject        string         `json:"subject"`
+	Key            string         `json:"key"`
+	RequestHash    string         `json:"request_hash"`
+	ResponseStatus sql.NullInt32  `json:"response_status"`
+	ContentType    sql.NullString `json:"content_type"`
+	ResponseBody   []byte         `json:"response_body"`
+	CreatedAt      time.Time      `json:"created_at"`
+	CompletedAt    sql.NullTime   `json:"completed_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Body           string    `json:"body"`
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	ModeratorID   uuid.NullUUID `json:"moderator_id"`
+	ReportID      uuid.NullUUID `json:"report_id"`
+	Action        string        `json:"action"`
+	TargetUserID  uuid.NullUUID `json:"target_user_id"`
+	TargetChirpID uuid.NullUUID `json:"target_chirp_id"`
+	Note          string        `json:"note"`
+}
+
+type Mute struct {
+	MuterID   uuid.UUID `json:"muter_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Notification struct {
+	ID             uuid.UUID     `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	UserID         uuid.UUID     `json:"user_id"`
+	Type           string        `json:"type"`
+	ActorID        uuid.NullUUID `json:"actor_id"`
+	ChirpID        uuid.NullUUID `json:"chirp_id"`
+	ReadAt         sql.NullTime  `json:"read_at"`
+	ConversationID uuid.NullUUID `json:"conversation_id"`
+}
+
+type RateLimit struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type RefreshToken struct {
@@ -28,11 +128,53 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime `json:"revoked_at"`
 }
 
+type Report struct {
+	ID             uuid.UUID     `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	ReporterID     uuid.UUID     `json:"reporter_id"`
+	ReportedUserID uuid.UUID     `json:"reported_user_id"`
+	ChirpID        uuid.NullUUID `json:"chirp_id"`
+	Reason         string        `json:"reason"`
+	Details        string        `json:"details"`
+	Status         string        `json:"status"`
+	ResolvedAt     sql.NullTime  `json:"resolved_at"`
+	ResolvedBy     uuid.NullUUID `json:"resolved_by"`
+}
+
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID             uuid.UUID    `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Email          string       `json:"email"`
+	HashedPassword string       `json:"hashed_password"`
+	IsChirpyRed    bool         `json:"is_chirpy_red"`
+	IsModerator    bool         `json:"is_moderator"`
+	Status         string       `json:"status"`
+	DeactivatedAt  sql.NullTime `json:"deactivated_at"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	UserID    uuid.NullUUID `json:"user_id"`
+	Url       string        `json:"url"`
+	Secret    string        `json:"secret"`
+	Events    []string      `json:"events"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode sql.NullInt32   `json:"last_status_code"`
+	LastError      sql.NullString  `json:"last_error"`
+	Traceparent    sql.NullString  `json:"traceparent"`
 }
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/dbtest"
+)
+
+// explain returns the plan PostgreSQL chooses for query. Sequential scans are
+// turned off, since on an empty table they would win over any index.
+func explain(t *testing.T, db *sql.DB, query string, args ...any) string {
+	t.Helper()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %s", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET enable_seqscan = off"); err != nil {
+		t.Fatalf("SET failed: %s", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		t.Fatalf("EXPLAIN failed: %s", err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatalf("Scan failed: %s", err)
+		}
+		plan = append(plan, line)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("EXPLAIN failed: %s", err)
+	}
+	return strings.Join(plan, "\n")
+}
+
+func TestQueryPlans(t *testing.T) {
+	db := dbtest.New(t, os.DirFS("../../sql/schema"))
+
+	tests := []struct {
+		name  string
+		query string
+		args  []any
+		index string
+	}{
+		{"GetChirpsFromAuthor", getChirpsFromAuthor, []any{uuid.New(), uuid.New(), "asc"}, "chirps_user_id_created_at_idx"},
+		{"GetRefreshTokensForUser", getRefreshTokensForUser, []any{uuid.New()}, "refresh_tokens_user_id_idx"},
+		{"RevokeRefreshTokensForUser", revokeRefreshTokensForUser, []any{uuid.New()}, "refresh_tokens_user_id_idx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := explain(t, db, tt.query, tt.args...)
+			if !strings.Contains(plan, tt.index) {
+				t.Fatalf("plan does not use %s:\n%s", tt.index, plan)
+			}
+		})
+	}
+}
+
+func TestChirpBodiesAreNotUnique(t *testing.T) {
+	db := dbtest.New(t, os.DirFS("../../sql/schema"))
+	q := New(db)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		user, err := q.CreateUser(ctx, CreateUserParams{Email: email, HashedPassword: "unused"})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %s", err)
+		}
+		if _, err := q.CreateChirp(ctx, CreateChirpParams{Body: "Hello, world!", UserID: user.ID, IsPublished: true}); err != nil {
+			t.Fatalf("CreateChirp failed for a body another user already posted: %s", err)
+		}
+	}
+}
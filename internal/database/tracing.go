@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/debobrad579/chirpy/internal/database"
+
+// tracedDB is a DBTX that records a span for each statement run within a
+// trace. Statements outside of one, such as the polling of background
+// workers, are not traced. It uses the global tracer provider, so it costs
+// next to nothing until tracing is set up.
+type tracedDB struct {
+	db DBTX
+}
+
+// Traced wraps db so that every query made through it is recorded as a span
+// named after the sqlc query, such as GetChirp.
+func Traced(db DBTX) DBTX {
+	return tracedDB{db: db}
+}
+
+// WithTracedTx is WithTx for a transaction whose queries should be traced
+// like those of a Queries made from Traced.
+func (q *Queries) WithTracedTx(tx *sql.Tx) *Queries {
+	return &Queries{db: Traced(tx)}
+}
+
+// queryName returns the name sqlc puts at the top of each query, which is
+// also the name of the method that runs it.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "query"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+func (t tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+
+	name := queryName(query)
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	result, err := t.db.ExecContext(ctx, query, args...)
+	if err == nil {
+		if n, err := result.RowsAffected(); err == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", n))
+		}
+	}
+	end(span, err)
+	return result, err
+}
+
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	stmt, err := t.db.PrepareContext(ctx, query)
+	end(span, err)
+	return stmt, err
+}
+
+// QueryContext ends its span once the query has been sent, not when the
+// rows have been read.
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	end(span, err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	// Err does not report sql.ErrNoRows, which is an answer rather than a
+	// failure.
+	end(span, row.Err())
+	return row
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// execDB is a DBTX whose ExecContext returns err, and which must not be used
+// for anything else.
+type execDB struct {
+	DBTX
+	err error
+}
+
+func (db execDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestQueryName(t *testing.T) {
+	if got := queryName(deleteChirp); got != "DeleteChirp" {
+		t.Fatalf("queryName is %q, expected DeleteChirp", got)
+	}
+	if got := queryName("SELECT 1"); got != "query" {
+		t.Fatalf("queryName is %q for a query without a name", got)
+	}
+}
+
+func TestTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	failure := errors.New("connection reset")
+	q := New(Traced(execDB{err: failure}))
+
+	if err := q.DeleteChirp(context.Background(), uuid.New()); !errors.Is(err, failure) {
+		t.Fatalf("DeleteChirp returned %v", err)
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("recorded %d spans for a query outside of a trace", n)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	q.DeleteChirp(ctx, uuid.New())
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, expected the query and its parent", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "DeleteChirp" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("recorded span %q, expected DeleteChirp as a child of the request", span.Name())
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("span status is %v, expected an error", span.Status())
+	}
+}
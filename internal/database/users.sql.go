@@ -10,8 +10,45 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE
+    users
+SET
+    email = 'deleted+' || id || '@chirpy.invalid',
+    hashed_password = '',
+    is_chirpy_red = FALSE,
+    is_moderator = FALSE,
+    status = 'deleted',
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+func (q *Queries) AnonymizeUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUser, id)
+	return err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT
+    COUNT(*)
+FROM
+    users
+WHERE
+    id = ANY ($1::uuid[])
+`
+
+func (q *Queries) CountUsers(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers, pq.Array(ids))
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
     VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, FALSE)
@@ -45,6 +82,22 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
+const deactivateUser = `-- name: DeactivateUser :exec
+UPDATE
+    users
+SET
+    status = 'deactivated',
+    deactivated_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deactivateUser, id)
+	return err
+}
+
 const deleteAllUsers = `-- name: DeleteAllUsers :exec
 DELETE FROM users
 `
@@ -54,9 +107,41 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const deleteUserPersonalData = `-- name: DeleteUserPersonalData :exec
+WITH deleted_tokens AS (
+    DELETE FROM refresh_tokens
+    WHERE refresh_tokens.user_id = $1),
+deleted_webhooks AS (
+    DELETE FROM webhooks
+    WHERE webhooks.user_id = $1),
+deleted_notifications AS (
+    DELETE FROM notifications
+    WHERE notifications.user_id = $1),
+deleted_blocks AS (
+    DELETE FROM blocks
+    WHERE blocker_id = $1)
+DELETE FROM mutes
+WHERE muter_id = $1
+`
+
+func (q *Queries) DeleteUserPersonalData(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserPersonalData, userID)
+	return err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
 SELECT
-    id, created_at, updated_at, email, hashed_password, is_chirpy_red
+    id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_moderator, status, deactivated_at
 FROM
     users
 WHERE
@@ -73,10 +158,152 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.Status,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT
+    id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_moderator, status, deactivated_at
+FROM
+    users
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.Status,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const getUserStatus = `-- name: GetUserStatus :one
+SELECT
+    status
+FROM
+    users
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetUserStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserStatus, id)
+	var status string
+	err := row.Scan(&status)
+	return status, err
+}
+
+const getUsersDueForDeletion = `-- name: GetUsersDueForDeletion :many
+SELECT
+    id
+FROM
+    users
+WHERE
+    status = 'deactivated'
+    AND deactivated_at <= NOW() - make_interval(secs => $1::float8)
+LIMIT $2::int
+FOR UPDATE
+    SKIP LOCKED
+`
+
+type GetUsersDueForDeletionParams struct {
+	GraceSeconds float64 `json:"grace_seconds"`
+	BatchSize    int32   `json:"batch_size"`
+}
+
+func (q *Queries) GetUsersDueForDeletion(ctx context.Context, arg GetUsersDueForDeletionParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersDueForDeletion, arg.GraceSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reactivateUser = `-- name: ReactivateUser :exec
+UPDATE
+    users
+SET
+    status = 'active',
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, reactivateUser, id)
+	return err
+}
+
+const setUserModerator = `-- name: SetUserModerator :exec
+UPDATE
+    users
+SET
+    is_moderator = $2,
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+type SetUserModeratorParams struct {
+	ID          uuid.UUID `json:"id"`
+	IsModerator bool      `json:"is_moderator"`
+}
+
+func (q *Queries) SetUserModerator(ctx context.Context, arg SetUserModeratorParams) error {
+	_, err := q.db.ExecContext(ctx, setUserModerator, arg.ID, arg.IsModerator)
+	return err
+}
+
+const setUserStatus = `-- name: SetUserStatus :exec
+UPDATE
+    users
+SET
+    status = $2,
+    updated_at = NOW()
+WHERE
+    id = $1
+`
+
+type SetUserStatusParams struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+}
+
+func (q *Queries) SetUserStatus(ctx context.Context, arg SetUserStatusParams) error {
+	_, err := q.db.ExecContext(ctx, setUserStatus, arg.ID, arg.Status)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE
     users
@@ -120,7 +347,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 	return i, err
 }
 
-const upgradeUser = `-- name: UpgradeUser :exec
+const upgradeUser = `-- name: UpgradeUser :execrows
 UPDATE
     users
 SET
@@ -129,7 +356,10 @@ WHERE
     id = $1
 `
 
-func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeUser, id)
-	return err
+func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, upgradeUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -0,0 +1,174 @@
+// Package dbtest gives tests disposable PostgreSQL and SQLite databases.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+
+	"github.com/debobrad579/chirpy/internal/sqlite"
+)
+
+// URLEnv names the environment variable holding the URL of the PostgreSQL
+// server to create test databases on. The role in it needs CREATEDB.
+const URLEnv = "CHIRPY_TEST_DB_URL"
+
+// binaryDirs are where distributions install PostgreSQL when its binaries
+// are not on the PATH.
+var binaryDirs = []string{
+	"/usr/lib/postgresql/*/bin",
+	"/usr/pgsql-*/bin",
+	"/usr/local/opt/postgresql*/bin",
+	"/opt/homebrew/opt/postgresql*/bin",
+}
+
+// New creates an empty database, applies the migrations in schema to it and
+// returns a connection to it. The database is dropped when the test ends.
+//
+// The database is created on the server at $CHIRPY_TEST_DB_URL. If that is
+// not set, a server is started for the test from the PostgreSQL installed on
+// the machine, listening only on a Unix socket. The test is skipped if
+// neither is available, which includes running as root, since PostgreSQL
+// will not start a server as root.
+func New(t testing.TB, schema fs.FS) *sql.DB {
+	t.Helper()
+
+	serverURL := os.Getenv(URLEnv)
+	if serverURL == "" {
+		serverURL = startServer(t)
+	}
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatalf("%s is not a valid URL: %s", URLEnv, err)
+	}
+
+	server, err := sql.Open("postgres", serverURL)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %s", URLEnv, err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	name := "chirpy_test_" + hex.EncodeToString(suffix)
+
+	ctx := context.Background()
+	if _, err := server.ExecContext(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("Failed to create test database: %s", err)
+	}
+	t.Cleanup(func() {
+		if _, err := server.ExecContext(ctx, "DROP DATABASE "+name+" WITH (FORCE)"); err != nil {
+			t.Errorf("Failed to drop test database %s: %s", name, err)
+		}
+	})
+
+	u.Path = "/" + name
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("Failed to open test database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := goose.NewProvider(goose.DialectPostgres, db, schema)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+	if _, err := migrations.Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate test database: %s", err)
+	}
+
+	return db
+}
+
+// NewSQLite creates a SQLite database in a temporary directory, applies the
+// migrations in schema to it and returns a connection to it. Unlike New, it
+// never skips.
+func NewSQLite(t testing.TB, schema fs.FS) *sql.DB {
+	t.Helper()
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := goose.NewProvider(goose.DialectSQLite3, db, schema)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+	if _, err := migrations.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate test database: %s", err)
+	}
+
+	return db
+}
+
+// startServer initializes a cluster in a temporary directory, starts it and
+// returns its URL. The server is stopped and its files removed when the test
+// ends.
+func startServer(t testing.TB) string {
+	t.Helper()
+
+	bin, err := findBinaries()
+	if err != nil {
+		t.Skipf("%s is not set and %s", URLEnv, err)
+	}
+	if os.Geteuid() == 0 {
+		t.Skipf("%s is not set and PostgreSQL refuses to run as root", URLEnv)
+	}
+
+	// Unix socket paths are limited to about 100 bytes, which rules out
+	// t.TempDir for tests with long names.
+	dir, err := os.MkdirTemp("", "chirpy-pg")
+	if err != nil {
+		t.Fatalf("Failed to create server directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	data := filepath.Join(dir, "data")
+	pgCtl := filepath.Join(bin, "pg_ctl")
+
+	run(t, filepath.Join(bin, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync")
+	run(t, pgCtl, "start", "-D", data, "-l", filepath.Join(dir, "server.log"), "-w",
+		"-o", "-k "+dir+" -c listen_addresses='' -F")
+	t.Cleanup(func() {
+		exec.Command(pgCtl, "stop", "-D", data, "-m", "immediate", "-w").Run()
+	})
+
+	return "postgres://postgres@/postgres?sslmode=disable&host=" + url.QueryEscape(dir)
+}
+
+// findBinaries returns the directory holding initdb and pg_ctl.
+func findBinaries() (string, error) {
+	if path, err := exec.LookPath("pg_ctl"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	for _, pattern := range binaryDirs {
+		dirs, _ := filepath.Glob(pattern)
+		for i := len(dirs) - 1; i >= 0; i-- {
+			if _, err := os.Stat(filepath.Join(dirs[i], "pg_ctl")); err == nil {
+				return dirs[i], nil
+			}
+		}
+	}
+	return "", errors.New("no PostgreSQL installation was found")
+}
+
+func run(t testing.TB, name string, args ...string) {
+	t.Helper()
+
+	if out, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		t.Fatalf("%s failed: %s\n%s", filepath.Base(name), err, out)
+	}
+}
@@ -0,0 +1,238 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+// Store is the subset of database.Queries needed to collect a user's data.
+type Store interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetAllChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	GetRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error)
+	GetMessagesSentByUser(ctx context.Context, userID uuid.UUID) ([]database.Message, error)
+	GetBlocks(ctx context.Context, blockerID uuid.UUID) ([]database.Block, error)
+	GetMutes(ctx context.Context, muterID uuid.UUID) ([]database.Mute, error)
+}
+
+type Profile struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Email         string     `json:"email"`
+	IsModerator   bool       `json:"is_moderator"`
+	Status        string     `json:"status"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+type Chirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	Published bool       `json:"published"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+}
+
+// Session describes a refresh token without the token itself, which would
+// otherwise let anyone holding the archive sign in as the user.
+type Session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Subscription is the user's current Chirpy Red state. Upgrades are not
+// recorded individually, so there is no history beyond that.
+type Subscription struct {
+	Plan        string `json:"plan"`
+	IsChirpyRed bool   `json:"is_chirpy_red"`
+}
+
+type Archive struct {
+	GeneratedAt  time.Time          `json:"generated_at"`
+	Profile      Profile            `json:"profile"`
+	Chirps       []Chirp            `json:"chirps"`
+	Messages     []database.Message `json:"messages"`
+	Sessions     []Session          `json:"sessions"`
+	Subscription Subscription       `json:"subscription"`
+	Blocks       []database.Block   `json:"blocks"`
+	Mutes        []database.Mute    `json:"mutes"`
+}
+
+// Collect gathers everything stored about userID.
+func Collect(ctx context.Context, store Store, userID uuid.UUID) (Archive, error) {
+	user, err := store.GetUserByID(ctx, userID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	chirps, err := store.GetAllChirpsForUser(ctx, userID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	tokens, err := store.GetRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	messages, err := store.GetMessagesSentByUser(ctx, userID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	blocks, err := store.GetBlocks(ctx, userID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	mutes, err := store.GetMutes(ctx, userID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	archive := Archive{
+		GeneratedAt: time.Now().UTC(),
+		Profile: Profile{
+			ID:            user.ID,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			Email:         user.Email,
+			IsModerator:   user.IsModerator,
+			Status:        user.Status,
+			DeactivatedAt: nullTime(user.DeactivatedAt.Time, user.DeactivatedAt.Valid),
+		},
+		Chirps:   make([]Chirp, 0, len(chirps)),
+		Messages: messages,
+		Sessions: make([]Session, 0, len(tokens)),
+		Subscription: Subscription{
+			Plan:        "free",
+			IsChirpyRed: user.IsChirpyRed,
+		},
+		Blocks: blocks,
+		Mutes:  mutes,
+	}
+	if user.IsChirpyRed {
+		archive.Subscription.Plan = "chirpy_red"
+	}
+
+	for _, chirp := range chirps {
+		archive.Chirps = append(archive.Chirps, Chirp{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+			Published: chirp.IsPublished,
+			PublishAt: nullTime(chirp.PublishAt.Time, chirp.PublishAt.Valid),
+			HiddenAt:  nullTime(chirp.HiddenAt.Time, chirp.HiddenAt.Valid),
+		})
+	}
+
+	for _, token := range tokens {
+		archive.Sessions = append(archive.Sessions, Session{
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			RevokedAt: nullTime(token.RevokedAt.Time, token.RevokedAt.Valid),
+		})
+	}
+
+	if archive.Messages == nil {
+		archive.Messages = []database.Message{}
+	}
+	if archive.Blocks == nil {
+		archive.Blocks = []database.Block{}
+	}
+	if archive.Mutes == nil {
+		archive.Mutes = []database.Mute{}
+	}
+
+	return archive, nil
+}
+
+func nullTime(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
+
+type file struct {
+	Name        string
+	Description string
+	Count       int
+	data        any
+}
+
+func (a Archive) files() []file {
+	return []file{
+		{"profile.json", "Your account details", 1, a.Profile},
+		{"chirps.json", "Every chirp you have written, including scheduled and hidden ones", len(a.Chirps), a.Chirps},
+		{"messages.json", "Direct messages you have sent", len(a.Messages), a.Messages},
+		{"sessions.json", "Sign-in sessions (refresh tokens, without the token values)", len(a.Sessions), a.Sessions},
+		{"subscription.json", "Your Chirpy Red subscription", 1, a.Subscription},
+		{"blocks.json", "Users you have blocked", len(a.Blocks), a.Blocks},
+		{"mutes.json", "Users you have muted", len(a.Mutes), a.Mutes},
+	}
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Chirpy data export</title>
+  </head>
+  <body>
+    <h1>Chirpy data export</h1>
+    <p>Account: {{.Profile.Email}} ({{.Profile.ID}})</p>
+    <p>Generated: {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+    <ul>
+{{- range .Files}}
+      <li><a href="{{.Name}}">{{.Name}}</a> &mdash; {{.Description}} ({{.Count}})</li>
+{{- end}}
+    </ul>
+  </body>
+</html>
+`))
+
+// Write encodes the archive as a ZIP file with one JSON document per kind of
+// data and an index.html describing them.
+func (a Archive) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	files := a.files()
+
+	for _, f := range files {
+		fw, err := zw.Create(f.Name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.Create("index.html")
+	if err != nil {
+		return err
+	}
+	err = indexTemplate.Execute(fw, struct {
+		Archive
+		Files []file
+	}{a, files})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
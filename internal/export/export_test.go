@@ -0,0 +1,125 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+type fakeStore struct {
+	user   database.User
+	chirps []database.Chirp
+	tokens []database.RefreshToken
+}
+
+func (s fakeStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if id != s.user.ID {
+		return database.User{}, sql.ErrNoRows
+	}
+	return s.user, nil
+}
+
+func (s fakeStore) GetAllChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return s.chirps, nil
+}
+
+func (s fakeStore) GetRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	return s.tokens, nil
+}
+
+func (s fakeStore) GetMessagesSentByUser(ctx context.Context, userID uuid.UUID) ([]database.Message, error) {
+	return nil, nil
+}
+
+func (s fakeStore) GetBlocks(ctx context.Context, blockerID uuid.UUID) ([]database.Block, error) {
+	return nil, nil
+}
+
+func (s fakeStore) GetMutes(ctx context.Context, muterID uuid.UUID) ([]database.Mute, error) {
+	return nil, nil
+}
+
+func TestCollectAndWrite(t *testing.T) {
+	store := fakeStore{
+		user: database.User{
+			ID:             uuid.New(),
+			Email:          "alice@example.com",
+			HashedPassword: "secret-hash",
+			IsChirpyRed:    true,
+			Status:         "active",
+		},
+		chirps: []database.Chirp{{ID: uuid.New(), Body: "hello", IsPublished: true}},
+		tokens: []database.RefreshToken{{
+			Token:     "secret-token",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}},
+	}
+
+	archive, err := Collect(context.Background(), store, store.user.ID)
+	if err != nil {
+		t.Fatalf("Collect failed: %s", err)
+	}
+	if archive.Subscription.Plan != "chirpy_red" {
+		t.Fatalf("subscription plan is %q, expected chirpy_red", archive.Subscription.Plan)
+	}
+
+	var buf bytes.Buffer
+	if err := archive.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("archive is not a valid zip: %s", err)
+	}
+
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %s", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", f.Name, err)
+		}
+		contents[f.Name] = string(data)
+
+		if strings.HasSuffix(f.Name, ".json") && !json.Valid(data) {
+			t.Fatalf("%s is not valid JSON", f.Name)
+		}
+		if strings.Contains(string(data), "secret-hash") || strings.Contains(string(data), "secret-token") {
+			t.Fatalf("%s leaks a credential", f.Name)
+		}
+	}
+
+	for _, name := range []string{"index.html", "profile.json", "chirps.json", "sessions.json", "subscription.json"} {
+		if _, ok := contents[name]; !ok {
+			t.Fatalf("archive is missing %s", name)
+		}
+	}
+	if !strings.Contains(contents["index.html"], "alice@example.com") {
+		t.Fatal("index.html does not name the account")
+	}
+	if !strings.Contains(contents["chirps.json"], "hello") {
+		t.Fatal("chirps.json does not contain the user's chirp")
+	}
+}
+
+func TestCollectUnknownUser(t *testing.T) {
+	_, err := Collect(context.Background(), fakeStore{}, uuid.New())
+	if err != sql.ErrNoRows {
+		t.Fatalf("Collect returned %v, expected sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// MaxLineSize is the longest JSONL line that will be read.
+const MaxLineSize = 64 * 1024
+
+// Row is one chirp read from an import file. Line is the 1-based line of the
+// file the row came from, counting the CSV header.
+type Row struct {
+	Line       int       `json:"line"`
+	ExternalID string    `json:"external_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RowError struct {
+	Line       int    `json:"line"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+type Result struct {
+	Imported int        `json:"imported"`
+	Skipped  int        `json:"skipped"`
+	Errors   []RowError `json:"errors"`
+}
+
+// DetectFormat picks a format from an explicit name, a content type or a file
+// name, in that order.
+func DetectFormat(format, contentType, name string) (string, error) {
+	switch strings.ToLower(format) {
+	case FormatJSONL, "ndjson":
+		return FormatJSONL, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown import format %q", format)
+	}
+
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return FormatCSV, nil
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		return FormatJSONL, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	}
+
+	return "", errors.New("could not determine import format")
+}
+
+// Read parses r and calls fn once per row. A row that cannot be parsed is
+// passed to fn with a non-nil error so the caller can report it and carry on.
+// Read stops early if fn returns an error, or if the file itself is unreadable.
+func Read(r io.Reader, format string, fn func(Row, error) error) error {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r, fn)
+	case FormatCSV:
+		return readCSV(r, fn)
+	default:
+		return fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+func readJSONL(r io.Reader, fn func(Row, error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record struct {
+			ExternalID string `json:"external_id"`
+			Body       string `json:"body"`
+			CreatedAt  string `json:"created_at"`
+		}
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			if err := fn(Row{Line: line}, errors.New("Invalid JSON")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		row, err := newRow(line, record.ExternalID, record.Body, record.CreatedAt)
+		if err := fn(row, err); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func readCSV(r io.Reader, fn func(Row, error) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"external_id", "body", "created_at"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("CSV header is missing the %s column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i := columns[name]; i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return err
+			}
+			if err := fn(Row{Line: parseErr.StartLine}, errors.New("Invalid CSV row")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		row, err := newRow(line, field(record, "external_id"), field(record, "body"), field(record, "created_at"))
+		if err := fn(row, err); err != nil {
+			return err
+		}
+	}
+}
+
+func newRow(line int, externalID, body, createdAt string) (Row, error) {
+	row := Row{Line: line, ExternalID: strings.TrimSpace(externalID), Body: body}
+
+	if row.ExternalID == "" {
+		return row, errors.New("external_id is required")
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(createdAt))
+	if err != nil {
+		return row, errors.New("created_at must be an RFC 3339 time")
+	}
+	row.CreatedAt = t.UTC()
+
+	return row, nil
+}
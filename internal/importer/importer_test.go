@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func collect(t *testing.T, input, format string) ([]Row, []RowError) {
+	t.Helper()
+
+	var rows []Row
+	var rowErrors []RowError
+	err := Read(strings.NewReader(input), format, func(row Row, err error) error {
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: row.Line, ExternalID: row.ExternalID, Error: err.Error()})
+			return nil
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	return rows, rowErrors
+}
+
+func TestReadJSONL(t *testing.T) {
+	input := `{"external_id": "1", "body": "hello", "created_at": "2020-01-02T03:04:05Z"}
+
+not json
+{"external_id": "", "body": "no id", "created_at": "2020-01-02T03:04:05Z"}
+{"external_id": "3", "body": "bad time", "created_at": "yesterday"}
+`
+	rows, rowErrors := collect(t, input, FormatJSONL)
+
+	if len(rows) != 1 || rows[0].ExternalID != "1" || rows[0].Body != "hello" || rows[0].Line != 1 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	if !rows[0].CreatedAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("created_at parsed as %s", rows[0].CreatedAt)
+	}
+
+	if len(rowErrors) != 3 {
+		t.Fatalf("expected 3 row errors, got %+v", rowErrors)
+	}
+	for i, line := range []int{3, 4, 5} {
+		if rowErrors[i].Line != line {
+			t.Fatalf("row error %d is on line %d, expected %d", i, rowErrors[i].Line, line)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	input := "body,external_id,created_at\n" +
+		"\"hello, world\",a,2020-01-02T03:04:05Z\n" +
+		"second,b,not a time\n" +
+		"\"multi\nline\",c,2021-01-01T00:00:00Z\n"
+	rows, rowErrors := collect(t, input, FormatCSV)
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", rows)
+	}
+	if rows[0].Body != "hello, world" || rows[0].ExternalID != "a" || rows[0].Line != 2 {
+		t.Fatalf("unexpected first row: %+v", rows[0])
+	}
+	if rows[1].Body != "multi\nline" || rows[1].Line != 4 {
+		t.Fatalf("unexpected second row: %+v", rows[1])
+	}
+
+	if len(rowErrors) != 1 || rowErrors[0].Line != 3 || rowErrors[0].ExternalID != "b" {
+		t.Fatalf("unexpected row errors: %+v", rowErrors)
+	}
+}
+
+func TestReadCSVMissingColumn(t *testing.T) {
+	err := Read(strings.NewReader("id,body\n1,hello\n"), FormatCSV, func(Row, error) error { return nil })
+	if err == nil {
+		t.Fatal("Read accepted a CSV file without the required columns")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		format, contentType, name, want string
+	}{
+		{"csv", "application/x-ndjson", "", FormatCSV},
+		{"", "text/csv; charset=utf-8", "", FormatCSV},
+		{"", "application/x-ndjson", "", FormatJSONL},
+		{"", "", "chirps.JSONL", FormatJSONL},
+		{"", "", "chirps.csv", FormatCSV},
+	}
+	for _, c := range cases {
+		got, err := DetectFormat(c.format, c.contentType, c.name)
+		if err != nil || got != c.want {
+			t.Fatalf("DetectFormat(%q, %q, %q) = %q, %v, expected %q", c.format, c.contentType, c.name, got, err, c.want)
+		}
+	}
+
+	if _, err := DetectFormat("xml", "", ""); err == nil {
+		t.Fatal("DetectFormat accepted an unknown format")
+	}
+	if _, err := DetectFormat("", "application/octet-stream", "chirps.txt"); err == nil {
+		t.Fatal("DetectFormat guessed a format it could not determine")
+	}
+}
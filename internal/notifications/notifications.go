@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+const (
+	TypeMention = "mention"
+	TypeMessage = "message"
+	TypeWarning = "warning"
+)
+
+const subscriberBuffer = 32
+
+// Mentions returns the email addresses mentioned in body as "@<email>".
+// Users have no handle other than their email, so that is what a mention
+// refers to.
+func Mentions(body string) []string {
+	var mentions []string
+	for _, word := range strings.Fields(body) {
+		if !strings.HasPrefix(word, "@") {
+			continue
+		}
+		email := strings.TrimRight(word[1:], ".,!?:;)")
+		if !strings.Contains(email, "@") || slices.Contains(mentions, email) {
+			continue
+		}
+		mentions = append(mentions, email)
+	}
+	return mentions
+}
+
+type Subscription struct {
+	C      <-chan database.Notification
+	c      chan database.Notification
+	userID uuid.UUID
+	hub    *Hub
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
+
+// Hub routes notifications to the live connections of the user they belong
+// to. A user may have several connections open at once.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uuid.UUID]map[*Subscription]struct{})}
+}
+
+func (h *Hub) Subscribe(userID uuid.UUID) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan database.Notification, subscriberBuffer)
+	s := &Subscription{C: c, c: c, userID: userID, hub: h}
+
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][s] = struct{}{}
+
+	return s
+}
+
+// Publish delivers n to each of its user's connections. A connection whose
+// buffer is full is dropped and its channel closed; the notification is
+// still stored, so the client can fetch it over REST after reconnecting.
+func (h *Hub) Publish(n database.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers[n.UserID] {
+		select {
+		case s.c <- n:
+		default:
+			h.remove(s)
+		}
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	subs, ok := h.subscribers[s.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[s]; !ok {
+		return
+	}
+
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subscribers, s.userID)
+	}
+	close(s.c)
+}
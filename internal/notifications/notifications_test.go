@@ -0,0 +1,61 @@
+package notifications
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+func TestMentions(t *testing.T) {
+	got := Mentions("hi @alice@example.com, and @bob@example.com! @alice@example.com @nobody")
+	want := []string{"alice@example.com", "bob@example.com"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Mentions returned %v, expected %v", got, want)
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+
+	first := hub.Subscribe(userID)
+	defer first.Close()
+	second := hub.Subscribe(userID)
+	defer second.Close()
+	other := hub.Subscribe(uuid.New())
+	defer other.Close()
+
+	n := database.Notification{ID: uuid.New(), UserID: userID}
+	hub.Publish(n)
+
+	if got := <-first.C; got.ID != n.ID {
+		t.Fatal("first connection did not receive the notification")
+	}
+	if got := <-second.C; got.ID != n.ID {
+		t.Fatal("second connection did not receive the notification")
+	}
+	if len(other.C) != 0 {
+		t.Fatal("another user's connection received the notification")
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	s := hub.Subscribe(userID)
+
+	for range subscriberBuffer + 1 {
+		hub.Publish(database.Notification{ID: uuid.New(), UserID: userID})
+	}
+
+	for range s.C {
+	}
+
+	s.Close()
+	if len(hub.subscribers) != 0 {
+		t.Fatal("Hub kept a dropped subscriber")
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limits table so that every instance
+// shares the same limits. Each Take locks the bucket's row for the length of a
+// short transaction.
+type PostgresStore struct {
+	conn *sql.DB
+	db   *database.Queries
+}
+
+func NewPostgresStore(conn *sql.DB) *PostgresStore {
+	return &PostgresStore{conn: conn, db: database.New(database.Traced(conn))}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	now = now.UTC()
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := s.db.WithTracedTx(tx)
+
+	if err := qtx.CreateRateLimit(ctx, database.CreateRateLimitParams{
+		Key:       key,
+		Tokens:    float64(policy.Limit),
+		UpdatedAt: now,
+	}); err != nil {
+		return Result{}, err
+	}
+
+	b, err := qtx.GetRateLimitForUpdate(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, result := Take(policy, b.Tokens, b.UpdatedAt, now)
+
+	if err := qtx.UpdateRateLimit(ctx, database.UpdateRateLimitParams{
+		Key:       key,
+		Tokens:    tokens,
+		UpdatedAt: now,
+	}); err != nil {
+		return Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+func (s *PostgresStore) Sweep(ctx context.Context, before time.Time) (int, error) {
+	n, err := s.db.DeleteStaleRateLimits(ctx, before.UTC())
+	return int(n), err
+}
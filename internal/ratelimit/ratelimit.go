@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy allows Limit requests per Window. Requests are paid for from a token
+// bucket that holds at most Limit tokens and refills continuously, so a client
+// that has been quiet can burst up to Limit requests at once.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+func (p Policy) refillRate() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It is
+	// zero when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take refills the bucket for key as of now and takes a token from it if
+	// one is available.
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+	// Sweep forgets buckets last used before the given time. A bucket idle
+	// for longer than its policy's window is full, which is the same as not
+	// having one.
+	Sweep(ctx context.Context, before time.Time) (int, error)
+}
+
+// Take applies policy to a bucket holding tokens as of updatedAt. It returns
+// the number of tokens left as of now along with the result. Stores use it so
+// that they all agree on the arithmetic.
+func Take(policy Policy, tokens float64, updatedAt, now time.Time) (float64, Result) {
+	capacity := float64(policy.Limit)
+	rate := policy.refillRate()
+
+	if elapsed := now.Sub(updatedAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	result := Result{Allowed: tokens >= 1}
+	if result.Allowed {
+		tokens--
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = seconds((capacity - tokens) / rate)
+
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// SetHeaders writes the RateLimit-* headers describing result, and
+// Retry-After when the request was refused.
+func SetHeaders(h http.Header, policy Policy, result Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+	if !result.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientIP returns the address of the client that sent r. X-Forwarded-For is
+// only trusted when the server sits behind a proxy that sets it, because
+// clients can otherwise send any value they like. Even then only the last
+// entry is used: the proxy appends the address it saw, and every entry before
+// that came from the client.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			forwarded := values[len(values)-1]
+			if ip := strings.TrimSpace(forwarded[strings.LastIndex(forwarded, ",")+1:]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps buckets in process. Limits are not shared between
+// instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(policy.Limit), updatedAt: now}
+	}
+
+	tokens, result := Take(policy, b.tokens, b.updatedAt, now)
+	s.buckets[key] = bucket{tokens: tokens, updatedAt: now}
+
+	return result, nil
+}
+
+func (s *MemoryStore) Sweep(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(before) {
+			delete(s.buckets, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{Name: "test", Limit: 3, Window: 3 * time.Second}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	for i := range testPolicy.Limit {
+		result, err := store.Take(context.Background(), "key", testPolicy, now)
+		if err != nil {
+			t.Fatalf("Take failed: %s", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("request %d was refused within the limit", i+1)
+		}
+		if want := testPolicy.Limit - i - 1; result.Remaining != want {
+			t.Fatalf("request %d left %d remaining, expected %d", i+1, result.Remaining, want)
+		}
+	}
+
+	result, _ := store.Take(context.Background(), "key", testPolicy, now)
+	if result.Allowed {
+		t.Fatal("request over the limit was allowed")
+	}
+	if result.RetryAfter != time.Second {
+		t.Fatalf("RetryAfter is %s, expected 1s", result.RetryAfter)
+	}
+	if result.Reset != 3*time.Second {
+		t.Fatalf("Reset is %s, expected 3s", result.Reset)
+	}
+
+	if result, _ := store.Take(context.Background(), "other", testPolicy, now); !result.Allowed {
+		t.Fatal("a different key shared the bucket")
+	}
+
+	result, _ = store.Take(context.Background(), "key", testPolicy, now.Add(time.Second))
+	if !result.Allowed {
+		t.Fatal("request was refused after a token refilled")
+	}
+}
+
+func TestTakeDoesNotOverfill(t *testing.T) {
+	now := time.Now()
+	tokens, result := Take(testPolicy, 0, now.Add(-time.Hour), now)
+	if !result.Allowed || tokens != float64(testPolicy.Limit-1) {
+		t.Fatalf("bucket refilled to %v tokens, expected %d", tokens, testPolicy.Limit-1)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	store.Take(context.Background(), "old", testPolicy, now.Add(-time.Hour))
+	store.Take(context.Background(), "new", testPolicy, now)
+
+	n, err := store.Sweep(context.Background(), now.Add(-time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("Sweep removed %d buckets (err %v), expected 1", n, err)
+	}
+	if _, ok := store.buckets["new"]; !ok {
+		t.Fatal("Sweep removed a recently used bucket")
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	h := http.Header{}
+	SetHeaders(h, testPolicy, Result{Allowed: false, Remaining: 0, Reset: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond})
+
+	want := map[string]string{
+		"RateLimit-Limit":     "3",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "3",
+		"RateLimit-Policy":    "3;w=3",
+		"Retry-After":         "1",
+	}
+	for name, value := range want {
+		if got := h.Get(name); got != value {
+			t.Fatalf("%s is %q, expected %q", name, got, value)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	if ip := ClientIP(r, false); ip != "192.0.2.1" {
+		t.Fatalf("ClientIP returned %s without trusting the proxy, expected 192.0.2.1", ip)
+	}
+	if ip := ClientIP(r, true); ip != "203.0.113.7" {
+		t.Fatalf("ClientIP returned %s trusting the proxy, expected 203.0.113.7", ip)
+	}
+
+	// The client sent X-Forwarded-For itself and the proxy appended to it.
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	if ip := ClientIP(r, true); ip != "203.0.113.7" {
+		t.Fatalf("ClientIP returned %s for a spoofed X-Forwarded-For, expected 203.0.113.7", ip)
+	}
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Add("X-Forwarded-For", "203.0.113.7")
+	if ip := ClientIP(r, true); ip != "203.0.113.7" {
+		t.Fatalf("ClientIP returned %s for a spoofed X-Forwarded-For header, expected 203.0.113.7", ip)
+	}
+}
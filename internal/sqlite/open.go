@@ -0,0 +1,38 @@
+// Package sqlite holds the queries of Chirpy's SQLite backend, generated by
+// sqlc from sql/sqlite. They mirror those of internal/database for users,
+// chirps and refresh tokens, with what PostgreSQL provides done by hand:
+//
+//   - gen_random_uuid() is a function registered with the driver.
+//   - NOW() is CAST(unixepoch('subsec') * 1000000 AS INTEGER), since Open
+//     stores timestamps as microseconds since the Unix epoch. Like NOW(), it
+//     has the same value everywhere in a statement.
+//   - Sorting by a parameter negates created_at for descending order instead
+//     of using one CASE for each direction.
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+)
+
+func init() {
+	sqlite.MustRegisterScalarFunction("gen_random_uuid", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return uuid.NewString(), nil
+	})
+}
+
+// Open opens the database file at path, creating it if it does not exist.
+// Queries rely on the connection settings it makes: timestamps are stored as
+// integer microseconds and foreign keys are enforced.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{
+		"_time_integer_format": {"unix_micro"},
+		"_inttotime":           {"true"},
+		"_pragma":              {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+	}
+	return sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+}
@@ -0,0 +1,465 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/auth"
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+// MemoryStore keeps everything in maps and loses it on exit. It has no blocks
+// or mutes, so listing chirps never leaves any out for the viewer.
+type MemoryStore struct {
+	mu     sync.Mutex
+	users  map[uuid.UUID]database.User
+	chirps map[uuid.UUID]database.Chirp
+	tokens map[string]database.RefreshToken
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:  make(map[uuid.UUID]database.User),
+		chirps: make(map[uuid.UUID]database.Chirp),
+		tokens: make(map[string]database.RefreshToken),
+	}
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// timestamp stores times as PostgreSQL would: in UTC, to the microsecond.
+func timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func nullTimestamp(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+	return sql.NullTime{Time: timestamp(t.Time), Valid: true}
+}
+
+func (s *MemoryStore) emailTaken(email string, except uuid.UUID) bool {
+	for _, user := range s.users {
+		if user.Email == email && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(arg.Email, uuid.Nil) {
+		return database.CreateUserRow{}, fmt.Errorf("a user with email %q already exists", arg.Email)
+	}
+
+	now := timestamp(time.Now())
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Status:         auth.StatusActive,
+	}
+	s.users[user.ID] = user
+
+	return database.CreateUserRow{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+	}, nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) GetUserStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	user, err := s.GetUserByID(ctx, id)
+	return user.Status, err
+}
+
+// UpdateUser leaves UpdatedAt alone, as the query it stands in for does.
+func (s *MemoryStore) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.UpdateUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.ID]
+	if !ok {
+		return database.UpdateUserRow{}, sql.ErrNoRows
+	}
+	if s.emailTaken(arg.Email, arg.ID) {
+		return database.UpdateUserRow{}, fmt.Errorf("a user with email %q already exists", arg.Email)
+	}
+
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	s.users[user.ID] = user
+
+	return database.UpdateUserRow{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+	}, nil
+}
+
+// updateUser applies fn to the user with the given ID, if there is one, and
+// reports whether there was.
+func (s *MemoryStore) updateUser(id uuid.UUID, fn func(*database.User)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return false
+	}
+	fn(&user)
+	s.users[id] = user
+	return true
+}
+
+func (s *MemoryStore) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	s.updateUser(id, func(user *database.User) {
+		now := timestamp(time.Now())
+		user.Status = auth.StatusDeactivated
+		user.DeactivatedAt = sql.NullTime{Time: now, Valid: true}
+		user.UpdatedAt = now
+	})
+	return nil
+}
+
+func (s *MemoryStore) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	s.updateUser(id, func(user *database.User) {
+		user.Status = auth.StatusActive
+		user.DeactivatedAt = sql.NullTime{}
+		user.UpdatedAt = timestamp(time.Now())
+	})
+	return nil
+}
+
+func (s *MemoryStore) SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) error {
+	s.updateUser(arg.ID, func(user *database.User) {
+		user.Status = arg.Status
+		user.UpdatedAt = timestamp(time.Now())
+	})
+	return nil
+}
+
+func (s *MemoryStore) SetUserModerator(ctx context.Context, arg database.SetUserModeratorParams) error {
+	s.updateUser(arg.ID, func(user *database.User) {
+		user.IsModerator = arg.IsModerator
+		user.UpdatedAt = timestamp(time.Now())
+	})
+	return nil
+}
+
+// UpgradeUser leaves UpdatedAt alone, as the query it stands in for does.
+func (s *MemoryStore) UpgradeUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	if !s.updateUser(id, func(user *database.User) { user.IsChirpyRed = true }) {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (s *MemoryStore) DeleteAllUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.users)
+	clear(s.chirps)
+	clear(s.tokens)
+	return nil
+}
+
+func (s *MemoryStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.Chirp{}, fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+
+	now := timestamp(time.Now())
+	chirp := database.Chirp{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		PublishAt:   nullTimestamp(arg.PublishAt),
+		IsPublished: arg.IsPublished,
+	}
+	s.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (s *MemoryStore) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok || !chirp.IsPublished {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+// findChirps returns the chirps that match, sorted by order. Like the queries
+// it stands in for, it returns nil rather than an empty slice.
+func (s *MemoryStore) findChirps(match func(database.Chirp) bool, order func(a, b database.Chirp) int) []database.Chirp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var chirps []database.Chirp
+	for _, chirp := range s.chirps {
+		if match(chirp) {
+			chirps = append(chirps, chirp)
+		}
+	}
+	slices.SortFunc(chirps, order)
+	return chirps
+}
+
+func byCreatedAt(sort string) func(a, b database.Chirp) int {
+	return func(a, b database.Chirp) int {
+		if sort == "desc" {
+			a, b = b, a
+		}
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), bytes.Compare(a.ID[:], b.ID[:]))
+	}
+}
+
+func listed(chirp database.Chirp) bool {
+	return chirp.IsPublished && !chirp.HiddenAt.Valid
+}
+
+func (s *MemoryStore) GetChirps(ctx context.Context, arg database.GetChirpsParams) ([]database.Chirp, error) {
+	return s.findChirps(listed, byCreatedAt(arg.Sort)), nil
+}
+
+func (s *MemoryStore) GetChirpsFromAuthor(ctx context.Context, arg database.GetChirpsFromAuthorParams) ([]database.Chirp, error) {
+	return s.findChirps(func(chirp database.Chirp) bool {
+		return chirp.UserID == arg.UserID && listed(chirp)
+	}, byCreatedAt(arg.Sort)), nil
+}
+
+func (s *MemoryStore) GetAllChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return s.findChirps(func(chirp database.Chirp) bool {
+		return chirp.UserID == userID
+	}, byCreatedAt("asc")), nil
+}
+
+func (s *MemoryStore) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.chirps, id)
+	return nil
+}
+
+func (s *MemoryStore) HideChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok {
+		return nil
+	}
+	now := timestamp(time.Now())
+	chirp.HiddenAt = sql.NullTime{Time: now, Valid: true}
+	chirp.UpdatedAt = now
+	s.chirps[id] = chirp
+	return nil
+}
+
+func (s *MemoryStore) GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return s.findChirps(func(chirp database.Chirp) bool {
+		return chirp.UserID == userID && !chirp.IsPublished
+	}, func(a, b database.Chirp) int {
+		return a.PublishAt.Time.Compare(b.PublishAt.Time)
+	}), nil
+}
+
+func (s *MemoryStore) GetScheduledChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok || chirp.IsPublished {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (s *MemoryStore) DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok || chirp.IsPublished {
+		return 0, nil
+	}
+	delete(s.chirps, id)
+	return 1, nil
+}
+
+func (s *MemoryStore) UpdateScheduledChirp(ctx context.Context, arg database.UpdateScheduledChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[arg.ID]
+	if !ok || chirp.IsPublished {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	chirp.Body = arg.Body
+	chirp.PublishAt = nullTimestamp(arg.PublishAt)
+	chirp.UpdatedAt = timestamp(time.Now())
+	s.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (s *MemoryStore) GetDueChirps(ctx context.Context, limit int32) ([]database.Chirp, error) {
+	now := time.Now()
+	chirps := s.findChirps(func(chirp database.Chirp) bool {
+		return !chirp.IsPublished && !chirp.PublishAt.Time.After(now)
+	}, func(a, b database.Chirp) int {
+		return a.PublishAt.Time.Compare(b.PublishAt.Time)
+	})
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+	}
+	return chirps, nil
+}
+
+func (s *MemoryStore) PublishChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok {
+		return nil
+	}
+	chirp.IsPublished = true
+	chirp.CreatedAt = chirp.PublishAt.Time
+	chirp.UpdatedAt = timestamp(time.Now())
+	s.chirps[id] = chirp
+	return nil
+}
+
+func (s *MemoryStore) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.RefreshToken{}, fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	if _, ok := s.tokens[arg.Token]; ok {
+		return database.RefreshToken{}, errors.New("refresh token already exists")
+	}
+
+	now := timestamp(time.Now())
+	token := database.RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		ExpiresAt: timestamp(arg.ExpiresAt),
+	}
+	s.tokens[token.Token] = token
+	return token, nil
+}
+
+func (s *MemoryStore) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refreshToken, ok := s.tokens[token]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	return refreshToken, nil
+}
+
+func (s *MemoryStore) GetRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []database.RefreshToken
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b database.RefreshToken) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.Token, b.Token))
+	})
+	return tokens, nil
+}
+
+// RevokeRefreshToken revokes the token again if it already was, as the query
+// it stands in for does.
+func (s *MemoryStore) RevokeRefreshToken(ctx context.Context, token string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refreshToken, ok := s.tokens[token]
+	if !ok {
+		return 0, nil
+	}
+	now := timestamp(time.Now())
+	refreshToken.RevokedAt = sql.NullTime{Time: now, Valid: true}
+	refreshToken.UpdatedAt = now
+	s.tokens[token] = refreshToken
+	return 1, nil
+}
+
+func (s *MemoryStore) RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp(time.Now())
+	for token, refreshToken := range s.tokens {
+		if refreshToken.UserID == userID && !refreshToken.RevokedAt.Valid {
+			refreshToken.RevokedAt = sql.NullTime{Time: now, Valid: true}
+			refreshToken.UpdatedAt = now
+			s.tokens[token] = refreshToken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/sqlite"
+)
+
+// SQLiteStore keeps everything in a SQLite database. It has no blocks or
+// mutes, so listing chirps never leaves any out for the viewer.
+type SQLiteStore struct {
+	q *sqlite.Queries
+}
+
+// NewSQLiteStore returns a store using db, which must have been opened with
+// sqlite.Open and migrated with sql/sqlite/schema.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{q: sqlite.New(db)}
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// convert converts each of items, keeping a nil slice nil.
+func convert[T, U any](items []T, fn func(T) U) []U {
+	if items == nil {
+		return nil
+	}
+	converted := make([]U, len(items))
+	for i, item := range items {
+		converted[i] = fn(item)
+	}
+	return converted
+}
+
+func toChirp(chirp sqlite.Chirp) database.Chirp {
+	return database.Chirp(chirp)
+}
+
+func (s *SQLiteStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	user, err := s.q.CreateUser(ctx, sqlite.CreateUserParams{Email: arg.Email, HashedPassword: arg.HashedPassword})
+	return database.CreateUserRow(user), err
+}
+
+func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	user, err := s.q.GetUserByEmail(ctx, email)
+	return database.User(user), err
+}
+
+func (s *SQLiteStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := s.q.GetUserByID(ctx, id)
+	return database.User(user), err
+}
+
+func (s *SQLiteStore) GetUserStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	return s.q.GetUserStatus(ctx, id)
+}
+
+func (s *SQLiteStore) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.UpdateUserRow, error) {
+	user, err := s.q.UpdateUser(ctx, sqlite.UpdateUserParams{ID: arg.ID, Email: arg.Email, HashedPassword: arg.HashedPassword})
+	return database.UpdateUserRow(user), err
+}
+
+func (s *SQLiteStore) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeactivateUser(ctx, id)
+}
+
+func (s *SQLiteStore) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	return s.q.ReactivateUser(ctx, id)
+}
+
+func (s *SQLiteStore) SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) error {
+	return s.q.SetUserStatus(ctx, sqlite.SetUserStatusParams{ID: arg.ID, Status: arg.Status})
+}
+
+func (s *SQLiteStore) SetUserModerator(ctx context.Context, arg database.SetUserModeratorParams) error {
+	return s.q.SetUserModerator(ctx, sqlite.SetUserModeratorParams{ID: arg.ID, IsModerator: arg.IsModerator})
+}
+
+func (s *SQLiteStore) UpgradeUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.q.UpgradeUser(ctx, id)
+}
+
+func (s *SQLiteStore) DeleteAllUsers(ctx context.Context) error {
+	return s.q.DeleteAllUsers(ctx)
+}
+
+func (s *SQLiteStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	chirp, err := s.q.CreateChirp(ctx, sqlite.CreateChirpParams{
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		PublishAt:   arg.PublishAt,
+		IsPublished: arg.IsPublished,
+	})
+	return toChirp(chirp), err
+}
+
+func (s *SQLiteStore) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := s.q.GetChirp(ctx, id)
+	return toChirp(chirp), err
+}
+
+func (s *SQLiteStore) GetChirps(ctx context.Context, arg database.GetChirpsParams) ([]database.Chirp, error) {
+	chirps, err := s.q.GetChirps(ctx, arg.Sort)
+	return convert(chirps, toChirp), err
+}
+
+func (s *SQLiteStore) GetChirpsFromAuthor(ctx context.Context, arg database.GetChirpsFromAuthorParams) ([]database.Chirp, error) {
+	chirps, err := s.q.GetChirpsFromAuthor(ctx, sqlite.GetChirpsFromAuthorParams{UserID: arg.UserID, Sort: arg.Sort})
+	return convert(chirps, toChirp), err
+}
+
+func (s *SQLiteStore) GetAllChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	chirps, err := s.q.GetAllChirpsForUser(ctx, userID)
+	return convert(chirps, toChirp), err
+}
+
+func (s *SQLiteStore) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteChirp(ctx, id)
+}
+
+func (s *SQLiteStore) HideChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.HideChirp(ctx, id)
+}
+
+func (s *SQLiteStore) GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	chirps, err := s.q.GetScheduledChirps(ctx, userID)
+	return convert(chirps, toChirp), err
+}
+
+func (s *SQLiteStore) GetScheduledChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := s.q.GetScheduledChirp(ctx, id)
+	return toChirp(chirp), err
+}
+
+func (s *SQLiteStore) DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.q.DeleteScheduledChirp(ctx, id)
+}
+
+func (s *SQLiteStore) UpdateScheduledChirp(ctx context.Context, arg database.UpdateScheduledChirpParams) (database.Chirp, error) {
+	chirp, err := s.q.UpdateScheduledChirp(ctx, sqlite.UpdateScheduledChirpParams{ID: arg.ID, Body: arg.Body, PublishAt: arg.PublishAt})
+	return toChirp(chirp), err
+}
+
+func (s *SQLiteStore) GetDueChirps(ctx context.Context, limit int32) ([]database.Chirp, error) {
+	chirps, err := s.q.GetDueChirps(ctx, int64(limit))
+	return convert(chirps, toChirp), err
+}
+
+func (s *SQLiteStore) PublishChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.PublishChirp(ctx, id)
+}
+
+func (s *SQLiteStore) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	token, err := s.q.CreateRefreshToken(ctx, sqlite.CreateRefreshTokenParams{Token: arg.Token, UserID: arg.UserID, ExpiresAt: arg.ExpiresAt})
+	return database.RefreshToken(token), err
+}
+
+func (s *SQLiteStore) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := s.q.GetRefreshToken(ctx, token)
+	return database.RefreshToken(refreshToken), err
+}
+
+func (s *SQLiteStore) GetRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	tokens, err := s.q.GetRefreshTokensForUser(ctx, userID)
+	return convert(tokens, func(token sqlite.RefreshToken) database.RefreshToken {
+		return database.RefreshToken(token)
+	}), err
+}
+
+func (s *SQLiteStore) RevokeRefreshToken(ctx context.Context, token string) (int64, error) {
+	return s.q.RevokeRefreshToken(ctx, token)
+}
+
+func (s *SQLiteStore) RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	return s.q.RevokeRefreshTokensForUser(ctx, userID)
+}
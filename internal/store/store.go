@@ -0,0 +1,88 @@
+// Package store describes the data Chirpy's core handlers need, so that they
+// can run against something other than PostgreSQL. database.Queries satisfies
+// every interface here.
+//
+// Lookups that find nothing return sql.ErrNoRows, as database.Queries does.
+package store
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+type Users interface {
+	// CreateUser fails if another user has the same email.
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error)
+	GetUserByEmail(ctx context.Context, email string) (database.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUserStatus(ctx context.Context, id uuid.UUID) (string, error)
+	UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.UpdateUserRow, error)
+	DeactivateUser(ctx context.Context, id uuid.UUID) error
+	ReactivateUser(ctx context.Context, id uuid.UUID) error
+	SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) error
+	SetUserModerator(ctx context.Context, arg database.SetUserModeratorParams) error
+	// DeleteAllUsers also deletes their chirps and refresh tokens.
+	DeleteAllUsers(ctx context.Context) error
+}
+
+// Chirps stores chirps. Only published chirps that have not been hidden are
+// listed, and GetChirp only finds published ones; scheduled chirps are reached
+// through GetScheduledChirp.
+type Chirps interface {
+	// CreateChirp fails if the author does not exist.
+	CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error)
+	GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	// GetChirps and GetChirpsFromAuthor sort by creation time, oldest first
+	// if Sort is "asc" and newest first if it is "desc". Chirps by authors
+	// who block the viewer, or whom the viewer mutes, are left out.
+	GetChirps(ctx context.Context, arg database.GetChirpsParams) ([]database.Chirp, error)
+	GetChirpsFromAuthor(ctx context.Context, arg database.GetChirpsFromAuthorParams) ([]database.Chirp, error)
+	GetAllChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	DeleteChirp(ctx context.Context, id uuid.UUID) error
+	HideChirp(ctx context.Context, id uuid.UUID) error
+	// GetScheduledChirps sorts by publish time, soonest first.
+	GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	GetScheduledChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	// DeleteScheduledChirp returns the number of chirps deleted, which is
+	// zero if the chirp has already been published.
+	DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error)
+	// UpdateScheduledChirp returns sql.ErrNoRows if the chirp has already
+	// been published.
+	UpdateScheduledChirp(ctx context.Context, arg database.UpdateScheduledChirpParams) (database.Chirp, error)
+	// GetDueChirps returns up to limit scheduled chirps whose publish time
+	// has passed, soonest first.
+	GetDueChirps(ctx context.Context, limit int32) ([]database.Chirp, error)
+	// PublishChirp publishes a scheduled chirp, which is then created at its
+	// publish time.
+	PublishChirp(ctx context.Context, id uuid.UUID) error
+}
+
+type RefreshTokens interface {
+	// CreateRefreshToken fails if the user does not exist.
+	CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error)
+	GetRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error)
+	// RevokeRefreshToken returns the number of tokens revoked, which is zero
+	// if the token does not exist.
+	RevokeRefreshToken(ctx context.Context, token string) (int64, error)
+	RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// Subscriptions tracks Chirpy Red, which users get by paying through Polka.
+type Subscriptions interface {
+	// UpgradeUser returns the number of users upgraded, which is zero if
+	// the user does not exist.
+	UpgradeUser(ctx context.Context, id uuid.UUID) (int64, error)
+}
+
+type Store interface {
+	Users
+	Chirps
+	RefreshTokens
+	Subscriptions
+}
+
+var _ Store = (*database.Queries)(nil)
@@ -0,0 +1,29 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/dbtest"
+	"github.com/debobrad579/chirpy/internal/store"
+	"github.com/debobrad579/chirpy/internal/store/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemoryStore()
+	})
+}
+
+func TestPostgres(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return database.New(dbtest.New(t, os.DirFS("../../sql/schema")))
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewSQLiteStore(dbtest.NewSQLite(t, os.DirFS("../../sql/sqlite/schema")))
+	})
+}
@@ -0,0 +1,619 @@
+// Package storetest checks that an implementation of store.Store behaves like
+// database.Queries does against PostgreSQL.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/auth"
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/store"
+)
+
+// Run runs the conformance suite. open is called for each subtest and must
+// return an empty store.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"Users", testUsers},
+		{"UserStatus", testUserStatus},
+		{"Subscriptions", testSubscriptions},
+		{"Chirps", testChirps},
+		{"ChirpOrder", testChirpOrder},
+		{"ScheduledChirps", testScheduledChirps},
+		{"PublishChirps", testPublishChirps},
+		{"RefreshTokens", testRefreshTokens},
+		{"DeleteAllUsers", testDeleteAllUsers},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, open(t))
+		})
+	}
+}
+
+// roundTime drops what a store is not expected to keep: the location and
+// anything finer than a microsecond.
+func roundTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func createUser(t *testing.T, s store.Store, email string) database.CreateUserRow {
+	t.Helper()
+
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{Email: email, HashedPassword: "hash of " + email})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %s", err)
+	}
+	return user
+}
+
+func createChirp(t *testing.T, s store.Store, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+
+	chirp, err := s.CreateChirp(context.Background(), database.CreateChirpParams{Body: body, UserID: userID, IsPublished: true})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %s", err)
+	}
+	return chirp
+}
+
+func createScheduledChirp(t *testing.T, s store.Store, userID uuid.UUID, body string, publishAt time.Time) database.Chirp {
+	t.Helper()
+
+	chirp, err := s.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:      body,
+		UserID:    userID,
+		PublishAt: sql.NullTime{Time: publishAt, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %s", err)
+	}
+	return chirp
+}
+
+func getUser(t *testing.T, s store.Store, id uuid.UUID) database.User {
+	t.Helper()
+
+	user, err := s.GetUserByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %s", err)
+	}
+	return user
+}
+
+func expectNoRows(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if err != sql.ErrNoRows {
+		t.Fatalf("%s returned %v, expected sql.ErrNoRows", what, err)
+	}
+}
+
+func chirpIDs(chirps []database.Chirp) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	return ids
+}
+
+func sameChirp(t *testing.T, got, want database.Chirp) {
+	t.Helper()
+
+	if got.ID != want.ID ||
+		got.Body != want.Body ||
+		got.UserID != want.UserID ||
+		got.IsPublished != want.IsPublished ||
+		!got.CreatedAt.Equal(want.CreatedAt) ||
+		!got.UpdatedAt.Equal(want.UpdatedAt) ||
+		got.PublishAt.Valid != want.PublishAt.Valid ||
+		!got.PublishAt.Time.Equal(want.PublishAt.Time) ||
+		got.HiddenAt.Valid != want.HiddenAt.Valid ||
+		!got.HiddenAt.Time.Equal(want.HiddenAt.Time) {
+		t.Fatalf("got chirp %+v, expected %+v", got, want)
+	}
+}
+
+func testUsers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	created := createUser(t, s, "walt@example.com")
+	if created.ID == uuid.Nil || created.Email != "walt@example.com" || created.IsChirpyRed {
+		t.Fatalf("CreateUser returned %+v", created)
+	}
+	if created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("CreateUser set CreatedAt %s and UpdatedAt %s", created.CreatedAt, created.UpdatedAt)
+	}
+
+	if _, err := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com", HashedPassword: "x"}); err == nil {
+		t.Fatal("CreateUser allowed a second user with the same email")
+	}
+
+	user, err := s.GetUserByEmail(ctx, "walt@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail failed: %s", err)
+	}
+	if user.ID != created.ID ||
+		user.HashedPassword != "hash of walt@example.com" ||
+		user.IsModerator ||
+		user.Status != auth.StatusActive ||
+		user.DeactivatedAt.Valid ||
+		!user.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("GetUserByEmail returned %+v", user)
+	}
+
+	if byID := getUser(t, s, created.ID); byID.Email != user.Email {
+		t.Fatalf("GetUserByID returned %+v", byID)
+	}
+
+	_, err = s.GetUserByEmail(ctx, "WALT@example.com")
+	expectNoRows(t, "GetUserByEmail with different case", err)
+	_, err = s.GetUserByID(ctx, uuid.New())
+	expectNoRows(t, "GetUserByID for a missing user", err)
+
+	other := createUser(t, s, "jesse@example.com")
+	if _, err := s.UpdateUser(ctx, database.UpdateUserParams{ID: other.ID, Email: "walt@example.com", HashedPassword: "x"}); err == nil {
+		t.Fatal("UpdateUser allowed taking another user's email")
+	}
+
+	updated, err := s.UpdateUser(ctx, database.UpdateUserParams{ID: created.ID, Email: "heisenberg@example.com", HashedPassword: "new hash"})
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %s", err)
+	}
+	if updated.ID != created.ID || updated.Email != "heisenberg@example.com" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("UpdateUser returned %+v", updated)
+	}
+	if user := getUser(t, s, created.ID); user.HashedPassword != "new hash" {
+		t.Fatalf("UpdateUser left the password hash as %q", user.HashedPassword)
+	}
+	// Keeping your own email is not a conflict.
+	if _, err := s.UpdateUser(ctx, database.UpdateUserParams{ID: created.ID, Email: "heisenberg@example.com", HashedPassword: "x"}); err != nil {
+		t.Fatalf("UpdateUser failed to keep the same email: %s", err)
+	}
+
+	_, err = s.UpdateUser(ctx, database.UpdateUserParams{ID: uuid.New(), Email: "nobody@example.com", HashedPassword: "x"})
+	expectNoRows(t, "UpdateUser for a missing user", err)
+}
+
+func testUserStatus(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created := createUser(t, s, "walt@example.com")
+
+	status, err := s.GetUserStatus(ctx, created.ID)
+	if err != nil || status != auth.StatusActive {
+		t.Fatalf("GetUserStatus returned %q, %v", status, err)
+	}
+	_, err = s.GetUserStatus(ctx, uuid.New())
+	expectNoRows(t, "GetUserStatus for a missing user", err)
+
+	if err := s.DeactivateUser(ctx, created.ID); err != nil {
+		t.Fatalf("DeactivateUser failed: %s", err)
+	}
+	user := getUser(t, s, created.ID)
+	if user.Status != auth.StatusDeactivated || !user.DeactivatedAt.Valid || user.DeactivatedAt.Time.Before(created.CreatedAt) {
+		t.Fatalf("deactivated user is %+v", user)
+	}
+	if user.UpdatedAt.Before(user.DeactivatedAt.Time) {
+		t.Fatalf("DeactivateUser left UpdatedAt at %s", user.UpdatedAt)
+	}
+
+	if err := s.ReactivateUser(ctx, created.ID); err != nil {
+		t.Fatalf("ReactivateUser failed: %s", err)
+	}
+	if user := getUser(t, s, created.ID); user.Status != auth.StatusActive || user.DeactivatedAt.Valid {
+		t.Fatalf("reactivated user is %+v", user)
+	}
+
+	if err := s.SetUserStatus(ctx, database.SetUserStatusParams{ID: created.ID, Status: auth.StatusSuspended}); err != nil {
+		t.Fatalf("SetUserStatus failed: %s", err)
+	}
+	if status, _ := s.GetUserStatus(ctx, created.ID); status != auth.StatusSuspended {
+		t.Fatalf("status is %q after SetUserStatus, expected %q", status, auth.StatusSuspended)
+	}
+
+	if err := s.SetUserModerator(ctx, database.SetUserModeratorParams{ID: created.ID, IsModerator: true}); err != nil {
+		t.Fatalf("SetUserModerator failed: %s", err)
+	}
+	if user := getUser(t, s, created.ID); !user.IsModerator {
+		t.Fatal("SetUserModerator did not make the user a moderator")
+	}
+
+	// Updating a user that does not exist is not an error.
+	missing := uuid.New()
+	for name, err := range map[string]error{
+		"DeactivateUser":   s.DeactivateUser(ctx, missing),
+		"ReactivateUser":   s.ReactivateUser(ctx, missing),
+		"SetUserStatus":    s.SetUserStatus(ctx, database.SetUserStatusParams{ID: missing, Status: auth.StatusSuspended}),
+		"SetUserModerator": s.SetUserModerator(ctx, database.SetUserModeratorParams{ID: missing, IsModerator: true}),
+	} {
+		if err != nil {
+			t.Fatalf("%s for a missing user failed: %s", name, err)
+		}
+	}
+}
+
+func testSubscriptions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created := createUser(t, s, "walt@example.com")
+
+	n, err := s.UpgradeUser(ctx, created.ID)
+	if err != nil || n != 1 {
+		t.Fatalf("UpgradeUser returned %d, %v", n, err)
+	}
+	if user := getUser(t, s, created.ID); !user.IsChirpyRed {
+		t.Fatal("UpgradeUser did not give the user Chirpy Red")
+	}
+
+	// Upgrading again is harmless.
+	if n, err := s.UpgradeUser(ctx, created.ID); err != nil || n != 1 {
+		t.Fatalf("second UpgradeUser returned %d, %v", n, err)
+	}
+
+	if n, err := s.UpgradeUser(ctx, uuid.New()); err != nil || n != 0 {
+		t.Fatalf("UpgradeUser for a missing user returned %d, %v", n, err)
+	}
+}
+
+func testChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+
+	if _, err := s.CreateChirp(ctx, database.CreateChirpParams{Body: "Who?", UserID: uuid.New(), IsPublished: true}); err == nil {
+		t.Fatal("CreateChirp allowed a chirp by a missing user")
+	}
+
+	chirp := createChirp(t, s, walt.ID, "Say my name")
+	if chirp.ID == uuid.Nil || chirp.UserID != walt.ID || chirp.Body != "Say my name" || !chirp.IsPublished {
+		t.Fatalf("CreateChirp returned %+v", chirp)
+	}
+	if chirp.PublishAt.Valid || chirp.HiddenAt.Valid || !chirp.UpdatedAt.Equal(chirp.CreatedAt) {
+		t.Fatalf("CreateChirp returned %+v", chirp)
+	}
+
+	got, err := s.GetChirp(ctx, chirp.ID)
+	if err != nil {
+		t.Fatalf("GetChirp failed: %s", err)
+	}
+	sameChirp(t, got, chirp)
+
+	_, err = s.GetChirp(ctx, uuid.New())
+	expectNoRows(t, "GetChirp for a missing chirp", err)
+
+	// Bodies need not be unique.
+	echo := createChirp(t, s, jesse.ID, "Say my name")
+
+	chirps, err := s.GetChirpsFromAuthor(ctx, database.GetChirpsFromAuthorParams{UserID: jesse.ID, Sort: "asc"})
+	if err != nil {
+		t.Fatalf("GetChirpsFromAuthor failed: %s", err)
+	}
+	if len(chirps) != 1 {
+		t.Fatalf("GetChirpsFromAuthor returned %d chirps, expected 1", len(chirps))
+	}
+	sameChirp(t, chirps[0], echo)
+
+	chirps, err = s.GetChirpsFromAuthor(ctx, database.GetChirpsFromAuthorParams{UserID: uuid.New(), Sort: "asc"})
+	if err != nil || len(chirps) != 0 {
+		t.Fatalf("GetChirpsFromAuthor for a missing user returned %d chirps, %v", len(chirps), err)
+	}
+
+	if err := s.HideChirp(ctx, echo.ID); err != nil {
+		t.Fatalf("HideChirp failed: %s", err)
+	}
+	hidden, err := s.GetChirp(ctx, echo.ID)
+	if err != nil {
+		t.Fatalf("GetChirp for a hidden chirp failed: %s", err)
+	}
+	if !hidden.HiddenAt.Valid || hidden.UpdatedAt.Before(hidden.HiddenAt.Time) {
+		t.Fatalf("hidden chirp is %+v", hidden)
+	}
+
+	chirps, err = s.GetChirps(ctx, database.GetChirpsParams{Sort: "asc"})
+	if err != nil {
+		t.Fatalf("GetChirps failed: %s", err)
+	}
+	if ids := chirpIDs(chirps); !slices.Equal(ids, []uuid.UUID{chirp.ID}) {
+		t.Fatalf("GetChirps returned %v, expected only %s", ids, chirp.ID)
+	}
+	if chirps, _ := s.GetChirpsFromAuthor(ctx, database.GetChirpsFromAuthorParams{UserID: jesse.ID, Sort: "asc"}); len(chirps) != 0 {
+		t.Fatal("GetChirpsFromAuthor listed a hidden chirp")
+	}
+
+	scheduled := createScheduledChirp(t, s, walt.ID, "Tread lightly", time.Now().Add(time.Hour))
+	all, err := s.GetAllChirpsForUser(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("GetAllChirpsForUser failed: %s", err)
+	}
+	if ids := chirpIDs(all); !slices.Equal(ids, []uuid.UUID{chirp.ID, scheduled.ID}) {
+		t.Fatalf("GetAllChirpsForUser returned %v, expected %s and %s", ids, chirp.ID, scheduled.ID)
+	}
+	if all, _ := s.GetAllChirpsForUser(ctx, jesse.ID); len(all) != 1 {
+		t.Fatalf("GetAllChirpsForUser returned %d chirps, expected the hidden one", len(all))
+	}
+
+	if err := s.DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("DeleteChirp failed: %s", err)
+	}
+	_, err = s.GetChirp(ctx, chirp.ID)
+	expectNoRows(t, "GetChirp for a deleted chirp", err)
+	if err := s.DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("DeleteChirp for a missing chirp failed: %s", err)
+	}
+	if err := s.HideChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("HideChirp for a missing chirp failed: %s", err)
+	}
+}
+
+func testChirpOrder(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+
+	var created []uuid.UUID
+	for i, body := range []string{"One", "Two", "Three", "Four"} {
+		author := walt
+		if i%2 == 1 {
+			author = jesse
+		}
+		created = append(created, createChirp(t, s, author.ID, body).ID)
+		// Keep creation times apart so the order is certain.
+		time.Sleep(2 * time.Millisecond)
+	}
+	reversed := slices.Clone(created)
+	slices.Reverse(reversed)
+
+	chirps, err := s.GetChirps(ctx, database.GetChirpsParams{Sort: "asc"})
+	if err != nil {
+		t.Fatalf("GetChirps failed: %s", err)
+	}
+	if ids := chirpIDs(chirps); !slices.Equal(ids, created) {
+		t.Fatalf("GetChirps sorted ascending returned %v, expected %v", ids, created)
+	}
+
+	chirps, err = s.GetChirps(ctx, database.GetChirpsParams{Sort: "desc"})
+	if err != nil {
+		t.Fatalf("GetChirps failed: %s", err)
+	}
+	if ids := chirpIDs(chirps); !slices.Equal(ids, reversed) {
+		t.Fatalf("GetChirps sorted descending returned %v, expected %v", ids, reversed)
+	}
+
+	chirps, err = s.GetChirpsFromAuthor(ctx, database.GetChirpsFromAuthorParams{UserID: walt.ID, Sort: "desc"})
+	if err != nil {
+		t.Fatalf("GetChirpsFromAuthor failed: %s", err)
+	}
+	if ids, want := chirpIDs(chirps), []uuid.UUID{created[2], created[0]}; !slices.Equal(ids, want) {
+		t.Fatalf("GetChirpsFromAuthor sorted descending returned %v, expected %v", ids, want)
+	}
+
+	chirps, err = s.GetChirpsFromAuthor(ctx, database.GetChirpsFromAuthorParams{UserID: jesse.ID, Sort: "asc"})
+	if err != nil {
+		t.Fatalf("GetChirpsFromAuthor failed: %s", err)
+	}
+	if ids, want := chirpIDs(chirps), []uuid.UUID{created[1], created[3]}; !slices.Equal(ids, want) {
+		t.Fatalf("GetChirpsFromAuthor sorted ascending returned %v, expected %v", ids, want)
+	}
+}
+
+func testScheduledChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+
+	later := roundTime(time.Now().Add(2 * time.Hour))
+	sooner := roundTime(time.Now().Add(time.Hour))
+	second := createScheduledChirp(t, s, walt.ID, "Later", later)
+	first := createScheduledChirp(t, s, walt.ID, "Sooner", sooner)
+	published := createChirp(t, s, walt.ID, "Now")
+
+	if second.IsPublished || !second.PublishAt.Valid || !second.PublishAt.Time.Equal(later) {
+		t.Fatalf("CreateChirp returned %+v for a scheduled chirp", second)
+	}
+
+	_, err := s.GetChirp(ctx, second.ID)
+	expectNoRows(t, "GetChirp for a scheduled chirp", err)
+	if chirps, _ := s.GetChirps(ctx, database.GetChirpsParams{Sort: "asc"}); len(chirps) != 1 {
+		t.Fatalf("GetChirps returned %d chirps, expected only the published one", len(chirps))
+	}
+
+	chirps, err := s.GetScheduledChirps(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("GetScheduledChirps failed: %s", err)
+	}
+	if ids, want := chirpIDs(chirps), []uuid.UUID{first.ID, second.ID}; !slices.Equal(ids, want) {
+		t.Fatalf("GetScheduledChirps returned %v, expected %v", ids, want)
+	}
+
+	got, err := s.GetScheduledChirp(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetScheduledChirp failed: %s", err)
+	}
+	sameChirp(t, got, second)
+
+	_, err = s.GetScheduledChirp(ctx, published.ID)
+	expectNoRows(t, "GetScheduledChirp for a published chirp", err)
+	_, err = s.GetScheduledChirp(ctx, uuid.New())
+	expectNoRows(t, "GetScheduledChirp for a missing chirp", err)
+
+	soonest := roundTime(time.Now().Add(30 * time.Minute))
+	updated, err := s.UpdateScheduledChirp(ctx, database.UpdateScheduledChirpParams{
+		ID:        second.ID,
+		Body:      "Edited",
+		PublishAt: sql.NullTime{Time: soonest, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("UpdateScheduledChirp failed: %s", err)
+	}
+	if updated.Body != "Edited" || !updated.PublishAt.Time.Equal(soonest) || updated.UpdatedAt.Before(second.UpdatedAt) || !updated.CreatedAt.Equal(second.CreatedAt) {
+		t.Fatalf("UpdateScheduledChirp returned %+v", updated)
+	}
+
+	chirps, _ = s.GetScheduledChirps(ctx, walt.ID)
+	if ids, want := chirpIDs(chirps), []uuid.UUID{second.ID, first.ID}; !slices.Equal(ids, want) {
+		t.Fatalf("GetScheduledChirps returned %v after rescheduling, expected %v", ids, want)
+	}
+
+	_, err = s.UpdateScheduledChirp(ctx, database.UpdateScheduledChirpParams{ID: published.ID, Body: "Edited"})
+	expectNoRows(t, "UpdateScheduledChirp for a published chirp", err)
+
+	if chirps, err := s.GetScheduledChirps(ctx, uuid.New()); err != nil || len(chirps) != 0 {
+		t.Fatalf("GetScheduledChirps for a missing user returned %d chirps, %v", len(chirps), err)
+	}
+
+	if n, err := s.DeleteScheduledChirp(ctx, published.ID); err != nil || n != 0 {
+		t.Fatalf("DeleteScheduledChirp for a published chirp returned %d, %v", n, err)
+	}
+	if _, err := s.GetChirp(ctx, published.ID); err != nil {
+		t.Fatalf("DeleteScheduledChirp deleted a published chirp: %v", err)
+	}
+	if n, err := s.DeleteScheduledChirp(ctx, first.ID); err != nil || n != 1 {
+		t.Fatalf("DeleteScheduledChirp returned %d, %v", n, err)
+	}
+	_, err = s.GetScheduledChirp(ctx, first.ID)
+	expectNoRows(t, "GetScheduledChirp after DeleteScheduledChirp", err)
+}
+
+func testPublishChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+
+	earlier := roundTime(time.Now().Add(-2 * time.Hour))
+	recently := roundTime(time.Now().Add(-time.Hour))
+	second := createScheduledChirp(t, s, walt.ID, "Recently", recently)
+	first := createScheduledChirp(t, s, walt.ID, "Earlier", earlier)
+	createScheduledChirp(t, s, walt.ID, "Later", time.Now().Add(time.Hour))
+	createChirp(t, s, walt.ID, "Now")
+
+	chirps, err := s.GetDueChirps(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetDueChirps failed: %s", err)
+	}
+	if ids, want := chirpIDs(chirps), []uuid.UUID{first.ID, second.ID}; !slices.Equal(ids, want) {
+		t.Fatalf("GetDueChirps returned %v, expected %v", ids, want)
+	}
+	if chirps, _ := s.GetDueChirps(ctx, 1); len(chirps) != 1 {
+		t.Fatalf("GetDueChirps with a limit of 1 returned %d chirps", len(chirps))
+	}
+
+	if err := s.PublishChirp(ctx, first.ID); err != nil {
+		t.Fatalf("PublishChirp failed: %s", err)
+	}
+	published, err := s.GetChirp(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetChirp after PublishChirp failed: %s", err)
+	}
+	if !published.IsPublished || !published.CreatedAt.Equal(earlier) || published.Body != first.Body {
+		t.Fatalf("PublishChirp left %+v", published)
+	}
+	_, err = s.GetScheduledChirp(ctx, first.ID)
+	expectNoRows(t, "GetScheduledChirp after PublishChirp", err)
+
+	chirps, _ = s.GetDueChirps(ctx, 10)
+	if ids, want := chirpIDs(chirps), []uuid.UUID{second.ID}; !slices.Equal(ids, want) {
+		t.Fatalf("GetDueChirps returned %v after PublishChirp, expected %v", ids, want)
+	}
+}
+
+func testRefreshTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	expiresAt := roundTime(time.Now().Add(time.Hour))
+
+	if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "orphan", UserID: uuid.New(), ExpiresAt: expiresAt}); err == nil {
+		t.Fatal("CreateRefreshToken allowed a token for a missing user")
+	}
+
+	var tokens []string
+	for _, token := range []string{"first", "second"} {
+		created, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: token, UserID: walt.ID, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("CreateRefreshToken failed: %s", err)
+		}
+		if created.Token != token || created.UserID != walt.ID || !created.ExpiresAt.Equal(expiresAt) || created.RevokedAt.Valid {
+			t.Fatalf("CreateRefreshToken returned %+v", created)
+		}
+		tokens = append(tokens, token)
+		time.Sleep(2 * time.Millisecond)
+	}
+	if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "first", UserID: jesse.ID, ExpiresAt: expiresAt}); err == nil {
+		t.Fatal("CreateRefreshToken allowed a duplicate token")
+	}
+	if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "jesse's", UserID: jesse.ID, ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("CreateRefreshToken failed: %s", err)
+	}
+
+	token, err := s.GetRefreshToken(ctx, "first")
+	if err != nil {
+		t.Fatalf("GetRefreshToken failed: %s", err)
+	}
+	if token.UserID != walt.ID || !token.ExpiresAt.Equal(expiresAt) || token.RevokedAt.Valid {
+		t.Fatalf("GetRefreshToken returned %+v", token)
+	}
+	_, err = s.GetRefreshToken(ctx, "missing")
+	expectNoRows(t, "GetRefreshToken for a missing token", err)
+
+	list, err := s.GetRefreshTokensForUser(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("GetRefreshTokensForUser failed: %s", err)
+	}
+	var listed []string
+	for _, token := range list {
+		listed = append(listed, token.Token)
+	}
+	if !slices.Equal(listed, tokens) {
+		t.Fatalf("GetRefreshTokensForUser returned %v, expected %v", listed, tokens)
+	}
+
+	n, err := s.RevokeRefreshToken(ctx, "first")
+	if err != nil || n != 1 {
+		t.Fatalf("RevokeRefreshToken returned %d, %v", n, err)
+	}
+	if token, _ := s.GetRefreshToken(ctx, "first"); !token.RevokedAt.Valid || token.UpdatedAt.Before(token.RevokedAt.Time) {
+		t.Fatalf("revoked token is %+v", token)
+	}
+	if n, err := s.RevokeRefreshToken(ctx, "missing"); err != nil || n != 0 {
+		t.Fatalf("RevokeRefreshToken for a missing token returned %d, %v", n, err)
+	}
+
+	if err := s.RevokeRefreshTokensForUser(ctx, walt.ID); err != nil {
+		t.Fatalf("RevokeRefreshTokensForUser failed: %s", err)
+	}
+	if token, _ := s.GetRefreshToken(ctx, "second"); !token.RevokedAt.Valid {
+		t.Fatal("RevokeRefreshTokensForUser left a token unrevoked")
+	}
+	if token, _ := s.GetRefreshToken(ctx, "jesse's"); token.RevokedAt.Valid {
+		t.Fatal("RevokeRefreshTokensForUser revoked another user's token")
+	}
+}
+
+func testDeleteAllUsers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	chirp := createChirp(t, s, walt.ID, "Say my name")
+	if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "token", UserID: walt.ID, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("CreateRefreshToken failed: %s", err)
+	}
+
+	if err := s.DeleteAllUsers(ctx); err != nil {
+		t.Fatalf("DeleteAllUsers failed: %s", err)
+	}
+
+	_, err := s.GetUserByID(ctx, walt.ID)
+	expectNoRows(t, "GetUserByID after DeleteAllUsers", err)
+	_, err = s.GetChirp(ctx, chirp.ID)
+	expectNoRows(t, "GetChirp after DeleteAllUsers", err)
+	_, err = s.GetRefreshToken(ctx, "token")
+	expectNoRows(t, "GetRefreshToken after DeleteAllUsers", err)
+
+	// The email is free again.
+	createUser(t, s, "walt@example.com")
+}
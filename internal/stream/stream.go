@@ -0,0 +1,165 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+const subscriberBuffer = 64
+
+type Filter struct {
+	AuthorID      uuid.UUID
+	Hashtag       string
+	HiddenAuthors []uuid.UUID
+}
+
+func (f Filter) Match(chirp database.Chirp) bool {
+	if slices.Contains(f.HiddenAuthors, chirp.UserID) {
+		return false
+	}
+
+	if f.AuthorID != uuid.Nil && chirp.UserID != f.AuthorID {
+		return false
+	}
+
+	if f.Hashtag != "" {
+		tag := strings.ToLower(strings.TrimPrefix(f.Hashtag, "#"))
+		for _, hashtag := range Hashtags(chirp.Body) {
+			if hashtag == tag {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Hashtags returns the lowercased tags in body, without the leading '#'.
+func Hashtags(body string) []string {
+	var tags []string
+	for _, word := range strings.Fields(body) {
+		if !strings.HasPrefix(word, "#") {
+			continue
+		}
+		tag := strings.TrimRightFunc(word[1:], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		if tag != "" {
+			tags = append(tags, strings.ToLower(tag))
+		}
+	}
+	return tags
+}
+
+// WriteEvent writes chirp, the JSON form of the chirp with ID id, as a
+// Server-Sent Event with the same ID.
+func WriteEvent(w io.Writer, id uuid.UUID, chirp any) error {
+	data, err := json.Marshal(chirp)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: chirp\ndata: %s\n\n", id, data)
+	return err
+}
+
+type Subscription struct {
+	C      <-chan database.Chirp
+	c      chan database.Chirp
+	filter Filter
+	broker *Broker
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.remove(s)
+}
+
+// Broker fans published chirps out to subscribers and keeps the most recent
+// ones so reconnecting clients can resume from their Last-Event-ID.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	replay      []database.Chirp
+	replaySize  int
+}
+
+func NewBroker(replaySize int) *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+		replaySize:  replaySize,
+	}
+}
+
+// Publish delivers chirp to every matching subscriber. Subscribers that are
+// too slow to keep up are dropped; their channel is closed so the client can
+// reconnect and resume from the replay buffer.
+func (b *Broker) Publish(chirp database.Chirp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.replay = append(b.replay, chirp)
+	if len(b.replay) > b.replaySize {
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+
+	for s := range b.subscribers {
+		if !s.filter.Match(chirp) {
+			continue
+		}
+		select {
+		case s.c <- chirp:
+		default:
+			b.remove(s)
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns the buffered chirps published
+// after lastEventID that match filter. If lastEventID is empty or no longer
+// buffered, nothing is replayed.
+func (b *Broker) Subscribe(filter Filter, lastEventID string) (*Subscription, []database.Chirp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []database.Chirp
+	if lastEventID != "" {
+		for i, chirp := range b.replay {
+			if chirp.ID.String() != lastEventID {
+				continue
+			}
+			for _, chirp := range b.replay[i+1:] {
+				if filter.Match(chirp) {
+					missed = append(missed, chirp)
+				}
+			}
+			break
+		}
+	}
+
+	c := make(chan database.Chirp, subscriberBuffer)
+	s := &Subscription{C: c, c: c, filter: filter, broker: b}
+	b.subscribers[s] = struct{}{}
+
+	return s, missed
+}
+
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subscribers[s]; !ok {
+		return
+	}
+	delete(b.subscribers, s)
+	close(s.c)
+}
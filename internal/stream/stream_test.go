@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+func TestHashtags(t *testing.T) {
+	got := Hashtags("Hello #Go and #golang, not# this #")
+	want := []string{"go", "golang"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Hashtags returned %v, expected %v", got, want)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	author := uuid.New()
+	chirp := database.Chirp{ID: uuid.New(), UserID: author, Body: "shipping #Chirpy today"}
+
+	cases := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{AuthorID: author}, true},
+		{Filter{AuthorID: uuid.New()}, false},
+		{Filter{Hashtag: "chirpy"}, true},
+		{Filter{Hashtag: "#CHIRPY"}, true},
+		{Filter{Hashtag: "other"}, false},
+		{Filter{AuthorID: author, Hashtag: "other"}, false},
+		{Filter{HiddenAuthors: []uuid.UUID{author}}, false},
+		{Filter{HiddenAuthors: []uuid.UUID{uuid.New()}}, true},
+	}
+
+	for _, c := range cases {
+		if got := c.filter.Match(chirp); got != c.want {
+			t.Fatalf("Filter %+v Match returned %t, expected %t", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker(10)
+	author := uuid.New()
+
+	all, _ := broker.Subscribe(Filter{}, "")
+	defer all.Close()
+	filtered, _ := broker.Subscribe(Filter{AuthorID: author}, "")
+	defer filtered.Close()
+
+	other := database.Chirp{ID: uuid.New(), UserID: uuid.New()}
+	mine := database.Chirp{ID: uuid.New(), UserID: author}
+	broker.Publish(other)
+	broker.Publish(mine)
+
+	if got := <-all.C; got.ID != other.ID {
+		t.Fatal("unfiltered subscriber did not receive first chirp")
+	}
+	if got := <-all.C; got.ID != mine.ID {
+		t.Fatal("unfiltered subscriber did not receive second chirp")
+	}
+	if got := <-filtered.C; got.ID != mine.ID {
+		t.Fatal("filtered subscriber received the wrong chirp")
+	}
+	if len(filtered.C) != 0 {
+		t.Fatal("filtered subscriber received a chirp from another author")
+	}
+}
+
+func TestBrokerReplay(t *testing.T) {
+	broker := NewBroker(3)
+
+	var chirps []database.Chirp
+	for range 5 {
+		chirp := database.Chirp{ID: uuid.New()}
+		chirps = append(chirps, chirp)
+		broker.Publish(chirp)
+	}
+
+	s, missed := broker.Subscribe(Filter{}, chirps[2].ID.String())
+	s.Close()
+	if len(missed) != 2 || missed[0].ID != chirps[3].ID || missed[1].ID != chirps[4].ID {
+		t.Fatalf("Subscribe replayed %d chirps, expected the 2 after Last-Event-ID", len(missed))
+	}
+
+	s, missed = broker.Subscribe(Filter{}, chirps[0].ID.String())
+	s.Close()
+	if len(missed) != 0 {
+		t.Fatal("Subscribe should not replay when Last-Event-ID has left the buffer")
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker(1)
+	s, _ := broker.Subscribe(Filter{}, "")
+
+	for range subscriberBuffer + 1 {
+		broker.Publish(database.Chirp{ID: uuid.New()})
+	}
+
+	for range s.C {
+	}
+
+	s.Close()
+}
+
+func TestWriteEvent(t *testing.T) {
+	chirp := database.Chirp{ID: uuid.New(), Body: "hello"}
+
+	var buf bytes.Buffer
+	if err := WriteEvent(&buf, chirp.ID, chirp); err != nil {
+		t.Fatalf("WriteEvent returned error: %v", err)
+	}
+
+	event := buf.String()
+	if !strings.HasPrefix(event, "id: "+chirp.ID.String()+"\nevent: chirp\ndata: {") {
+		t.Fatalf("WriteEvent wrote unexpected event: %q", event)
+	}
+	if !strings.HasSuffix(event, "}\n\n") {
+		t.Fatalf("WriteEvent did not terminate the event: %q", event)
+	}
+}
@@ -0,0 +1,98 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCert(t *testing.T, certFile, keyFile, name string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %s", err)
+	}
+
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	os.Chtimes(certFile, modTime, modTime)
+	os.Chtimes(keyFile, modTime, modTime)
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	cert, _ := r.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	now := time.Now()
+
+	writeCert(t, certFile, keyFile, "first", now.Add(-time.Minute))
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewReloader failed: %s", err)
+	}
+	if name := commonName(t, r); name != "first" {
+		t.Fatalf("serving %q, expected first", name)
+	}
+
+	if reloaded, err := r.Reload(); err != nil || reloaded {
+		t.Fatalf("Reload reloaded unchanged files (reloaded %v, err %v)", reloaded, err)
+	}
+
+	writeCert(t, certFile, keyFile, "second", now)
+	if reloaded, err := r.Reload(); err != nil || !reloaded {
+		t.Fatalf("Reload missed changed files (reloaded %v, err %v)", reloaded, err)
+	}
+	if name := commonName(t, r); name != "second" {
+		t.Fatalf("serving %q after reload, expected second", name)
+	}
+
+	os.WriteFile(keyFile, []byte("not a key"), 0o600)
+	os.Chtimes(keyFile, now.Add(time.Minute), now.Add(time.Minute))
+	if _, err := r.Reload(); err == nil {
+		t.Fatal("Reload accepted an invalid key")
+	}
+	if name := commonName(t, r); name != "second" {
+		t.Fatalf("serving %q after a failed reload, expected second", name)
+	}
+}
+
+func TestNewReloaderMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")); err == nil {
+		t.Fatal("NewReloader accepted missing files")
+	}
+}
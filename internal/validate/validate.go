@@ -0,0 +1,185 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+const ContentType = "application/problem+json"
+
+// FieldError points at the member of the request body that failed
+// validation, using a JSON Pointer fragment such as "#/email".
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Detail  string `json:"detail"`
+}
+
+// Problem is an RFC 9457 problem details object. Errors is an extension
+// member listing each invalid field.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func WriteProblem(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.Error("Error encoding response", "error", err)
+	}
+}
+
+func pointer(field string) string {
+	return "#/" + strings.ReplaceAll(field, ".", "/")
+}
+
+// DecodeJSON decodes a single JSON object from the request body into dst. It
+// rejects bodies larger than maxBytes, unknown fields, values of the wrong
+// type and trailing data, and describes what was wrong in the returned
+// problem.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any, maxBytes int64) *Problem {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeProblem(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeProblem(err)
+		}
+		return NewProblem(http.StatusBadRequest, "Request body must contain a single JSON object")
+	}
+
+	return nil
+}
+
+func decodeProblem(err error) *Problem {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return NewProblem(http.StatusBadRequest, "Request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return NewProblem(http.StatusBadRequest, "Request body is not valid JSON")
+	case errors.As(err, &syntaxErr):
+		return NewProblem(http.StatusBadRequest, fmt.Sprintf("Request body is not valid JSON (at offset %d)", syntaxErr.Offset))
+	case errors.As(err, &maxBytesErr):
+		return NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return NewProblem(http.StatusBadRequest, "Request body must be a JSON object")
+		}
+		p := NewProblem(http.StatusBadRequest, "Request body has invalid fields")
+		p.Errors = []FieldError{{Pointer: pointer(typeErr.Field), Detail: fmt.Sprintf("Must be of type %s", jsonTypeName(typeErr.Type.Kind().String()))}}
+		return p
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strings.CutPrefix(err.Error(), "json: unknown field ")
+		field = strings.Trim(field, `"`)
+		p := NewProblem(http.StatusBadRequest, "Request body has invalid fields")
+		p.Errors = []FieldError{{Pointer: pointer(field), Detail: "Unknown field"}}
+		return p
+	default:
+		// Errors from a type's UnmarshalJSON, such as a malformed time or
+		// UUID, do not say which field they came from.
+		return NewProblem(http.StatusBadRequest, "Request body has an invalid value")
+	}
+}
+
+func jsonTypeName(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "bool":
+		return "boolean"
+	case kind == "slice", kind == "array":
+		return "array"
+	case kind == "struct", kind == "map":
+		return "object"
+	default:
+		return kind
+	}
+}
+
+// Validator collects field errors so that a client hears about every problem
+// with a request at once.
+type Validator struct {
+	errors []FieldError
+}
+
+// Check records detail against field unless ok.
+func (v *Validator) Check(ok bool, field, detail string) {
+	if !ok {
+		v.errors = append(v.errors, FieldError{Pointer: pointer(field), Detail: detail})
+	}
+}
+
+// CheckError records err against field if it is not nil.
+func (v *Validator) CheckError(field string, err error) {
+	if err != nil {
+		v.Check(false, field, err.Error())
+	}
+}
+
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "Is required")
+}
+
+func (v *Validator) MaxLength(field, value string, n int) {
+	v.Check(utf8.RuneCountInString(value) <= n, field, fmt.Sprintf("Must be at most %d characters", n))
+}
+
+// Email checks that a non-empty value is a bare email address. Empty values
+// are left to Required.
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	v.Check(err == nil && addr.Address == value, field, "Must be a valid email address")
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Problem returns a 400 problem listing every field error, or nil if there
+// are none.
+func (v *Validator) Problem() *Problem {
+	if v.Valid() {
+		return nil
+	}
+	p := NewProblem(http.StatusBadRequest, "Request body has invalid fields")
+	p.Errors = v.errors
+	return p
+}
@@ -0,0 +1,137 @@
+package validate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testParams struct {
+	Email string `json:"email"`
+	Count int    `json:"count"`
+}
+
+func decode(body string, maxBytes int64) (testParams, *Problem) {
+	var params testParams
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	p := DecodeJSON(httptest.NewRecorder(), r, &params, maxBytes)
+	return params, p
+}
+
+func TestDecodeJSON(t *testing.T) {
+	params, p := decode(`{"email": "a@example.com", "count": 2}`, 1024)
+	if p != nil {
+		t.Fatalf("valid body was rejected: %s", p)
+	}
+	if params.Email != "a@example.com" || params.Count != 2 {
+		t.Fatalf("decoded %+v", params)
+	}
+}
+
+func TestDecodeJSONRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		pointer string
+	}{
+		{"empty", ``, http.StatusBadRequest, ""},
+		{"malformed", `{"email": `, http.StatusBadRequest, ""},
+		{"syntax", `{"email" "a"}`, http.StatusBadRequest, ""},
+		{"not an object", `[]`, http.StatusBadRequest, ""},
+		{"unknown field", `{"emial": "a@example.com"}`, http.StatusBadRequest, "#/emial"},
+		{"wrong type", `{"count": "two"}`, http.StatusBadRequest, "#/count"},
+		{"trailing data", `{} {}`, http.StatusBadRequest, ""},
+		{"too large", `{"email": "` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		_, p := decode(tt.body, 64)
+		if p == nil {
+			t.Fatalf("%s: body was accepted", tt.name)
+		}
+		if p.Status != tt.status {
+			t.Fatalf("%s: status is %d, expected %d", tt.name, p.Status, tt.status)
+		}
+		if tt.pointer != "" && (len(p.Errors) != 1 || p.Errors[0].Pointer != tt.pointer) {
+			t.Fatalf("%s: errors are %+v, expected one for %s", tt.name, p.Errors, tt.pointer)
+		}
+	}
+}
+
+func TestValidator(t *testing.T) {
+	var v Validator
+	v.Required("email", "")
+	v.Email("email", "")
+	v.Required("password", "secret")
+	v.MaxLength("body", "hello", 3)
+
+	p := v.Problem()
+	if p == nil {
+		t.Fatal("Problem returned nil with invalid fields")
+	}
+	want := []FieldError{
+		{Pointer: "#/email", Detail: "Is required"},
+		{Pointer: "#/body", Detail: "Must be at most 3 characters"},
+	}
+	if len(p.Errors) != len(want) {
+		t.Fatalf("errors are %+v, expected %+v", p.Errors, want)
+	}
+	for i := range want {
+		if p.Errors[i] != want[i] {
+			t.Fatalf("errors are %+v, expected %+v", p.Errors, want)
+		}
+	}
+
+	var ok Validator
+	ok.Required("email", "a@example.com")
+	if ok.Problem() != nil {
+		t.Fatal("Problem returned a problem with no field errors")
+	}
+}
+
+func TestEmail(t *testing.T) {
+	valid := []string{"a@example.com", "first.last+tag@sub.example.org"}
+	invalid := []string{"a", "a@", "@example.com", "Name <a@example.com>", "a@example.com "}
+
+	for _, email := range valid {
+		var v Validator
+		if v.Email("email", email); !v.Valid() {
+			t.Fatalf("%q was rejected", email)
+		}
+	}
+	for _, email := range invalid {
+		var v Validator
+		if v.Email("email", email); v.Valid() {
+			t.Fatalf("%q was accepted", email)
+		}
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteProblem(w, NewProblem(http.StatusNotFound, "Chirp not found"))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status is %d, expected 404", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("Content-Type is %q, expected %q", ct, ContentType)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("response is not JSON: %s", err)
+	}
+	want := map[string]any{"type": "about:blank", "title": "Not Found", "status": float64(404), "detail": "Chirp not found"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s is %v, expected %v", k, got[k], v)
+		}
+	}
+	if _, ok := got["errors"]; ok {
+		t.Fatal("errors is present with no field errors")
+	}
+}
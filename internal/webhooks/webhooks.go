@@ -0,0 +1,225 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+	EventUserUpgraded = "user.upgraded"
+)
+
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	MaxAttempts = 8
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+var Events = []string{
+	EventChirpCreated,
+	EventChirpDeleted,
+	EventUserUpgraded,
+}
+
+func ValidEvent(event string) bool {
+	return slices.Contains(Events, event)
+}
+
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func NewPayload(event string, data any) (json.RawMessage, error) {
+	return json.Marshal(struct {
+		Event     string    `json:"event"`
+		CreatedAt time.Time `json:"created_at"`
+		Data      any       `json:"data"`
+	}{event, time.Now().UTC(), data})
+}
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the value of the signature header for body, in the form
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">".
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := timestamp.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", t, signature(secret, t, body))
+}
+
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var sig string
+
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return errors.New("malformed signature header")
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.New("malformed signature timestamp")
+			}
+			timestamp = t
+		case "v1":
+			sig = value
+		}
+	}
+
+	if timestamp == 0 || sig == "" {
+		return errors.New("malformed signature header")
+	}
+
+	if time.Since(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, timestamp, body))) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+// Backoff returns how long to wait before retrying a delivery that has
+// failed attempts times.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	backoff := baseBackoff
+	for range attempts - 1 {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// ErrPrivateAddress is returned when a delivery would connect to an address
+// that is not public.
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// PublicAddr reports whether webhooks may be sent to ip. Loopback, private,
+// link-local and unspecified addresses would let a user make the server send
+// requests into its own network.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// PublicHost reports whether host, the host name of a webhook URL, may be
+// public. Names other than localhost are only checked once they are resolved,
+// when a delivery connects.
+func PublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return PublicAddr(ip)
+	}
+	return true
+}
+
+// checkDial refuses connections to addresses that are not public. It runs
+// after the host name has been resolved, so it also holds when the name is
+// rebound to another address after the webhook was registered.
+func checkDial(network, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(addr.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr.Addr())
+	}
+	return nil
+}
+
+// NewClient returns a client for deliveries that only connects to public
+// addresses, including when following redirects. It ignores proxy settings,
+// since a proxy would connect on its behalf.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: checkDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+func Deliver(ctx context.Context, client *http.Client, url, secret, deliveryID, event string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), payload))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
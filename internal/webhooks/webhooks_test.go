@@ -0,0 +1,144 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := "secret"
+	body := []byte(`{"event":"chirp.created"}`)
+
+	header := Sign(secret, time.Now(), body)
+
+	if err := Verify(secret, header, body, time.Minute); err != nil {
+		t.Fatalf("Verify returned error for valid signature: %v", err)
+	}
+
+	if err := Verify("wrongsecret", header, body, time.Minute); err == nil {
+		t.Fatal("Verify should fail with wrong secret")
+	}
+
+	if err := Verify(secret, header, []byte(`{"event":"chirp.deleted"}`), time.Minute); err == nil {
+		t.Fatal("Verify should fail for tampered body")
+	}
+
+	old := Sign(secret, time.Now().Add(-time.Hour), body)
+	if err := Verify(secret, old, body, time.Minute); err == nil {
+		t.Fatal("Verify should fail for stale timestamp")
+	}
+
+	if err := Verify(secret, "garbage", body, time.Minute); err == nil {
+		t.Fatal("Verify should fail for malformed header")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if Backoff(0) != 0 {
+		t.Fatalf("Backoff(0) should be 0, got %s", Backoff(0))
+	}
+
+	if Backoff(1) != baseBackoff {
+		t.Fatalf("Backoff(1) should be %s, got %s", baseBackoff, Backoff(1))
+	}
+
+	for attempts := 2; attempts <= MaxAttempts; attempts++ {
+		if Backoff(attempts) < Backoff(attempts-1) {
+			t.Fatalf("Backoff(%d) is shorter than Backoff(%d)", attempts, attempts-1)
+		}
+	}
+
+	if Backoff(100) != maxBackoff {
+		t.Fatalf("Backoff should be capped at %s, got %s", maxBackoff, Backoff(100))
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	secret := "secret"
+	payload, err := NewPayload(EventChirpCreated, map[string]string{"body": "hello"})
+	if err != nil {
+		t.Fatalf("NewPayload returned error: %v", err)
+	}
+
+	var gotEvent, gotDelivery string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		gotEvent = r.Header.Get(EventHeader)
+		gotDelivery = r.Header.Get(DeliveryHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	status, err := Deliver(context.Background(), receiver.Client(), receiver.URL, secret, "delivery-1", EventChirpCreated, payload)
+	if err != nil {
+		t.Fatalf("Deliver returned error: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Fatalf("Deliver returned wrong status; expected %d, got %d", http.StatusNoContent, status)
+	}
+	if gotEvent != EventChirpCreated || gotDelivery != "delivery-1" {
+		t.Fatalf("receiver got wrong headers: event %q, delivery %q", gotEvent, gotDelivery)
+	}
+
+	status, err = Deliver(context.Background(), receiver.Client(), receiver.URL, "wrongsecret", "delivery-2", EventChirpCreated, payload)
+	if err == nil {
+		t.Fatal("Deliver should fail when the receiver rejects the signature")
+	}
+	if status != http.StatusUnauthorized {
+		t.Fatalf("Deliver returned wrong status; expected %d, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestPublicHost(t *testing.T) {
+	tests := map[string]bool{
+		"example.com":     true,
+		"93.184.215.14":   true,
+		"2606:4700::1111": true,
+		"localhost":       false,
+		"api.localhost.":  false,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"::ffff:10.0.0.1": false,
+	}
+	for host, public := range tests {
+		if got := PublicHost(host); got != public {
+			t.Errorf("PublicHost(%q) = %t, expected %t", host, got, public)
+		}
+	}
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	// The receiver's name resolves to loopback, as a rebound name would.
+	url := strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+	_, err := Deliver(context.Background(), NewClient(time.Second), url, "secret", "delivery-1", EventChirpCreated, []byte("{}"))
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("Deliver to %s returned %v, expected ErrPrivateAddress", url, err)
+	}
+	if called {
+		t.Fatal("receiver got the delivery")
+	}
+}
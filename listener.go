@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	chirpChannel        = "chirp_published"
+	notificationChannel = "notification_created"
+	chirpReplaySize     = 256
+	listenerPingPeriod  = 90 * time.Second
+	listenerName        = "database listener"
+
+	streamHeartbeatPeriod    = 15 * time.Second
+	websocketHeartbeatPeriod = 30 * time.Second
+	websocketWriteTimeout    = 10 * time.Second
+)
+
+// runListener forwards PostgreSQL notifications to the in-process chirp
+// stream and notification hub. Every instance listens, so each one sees every
+// event no matter which instance caused it.
+func (cfg *apiConfig) runListener(ctx context.Context, dbURL string) {
+	cfg.workers.register(listenerName, 0)
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		// Every event but a successful connection carries an error.
+		cfg.workers.report(listenerName, err)
+		if err != nil {
+			slog.Error("Error in database listener", "error", err)
+		}
+	})
+	defer listener.Close()
+
+	// Listen blocks until the database is reachable, so closing the listener
+	// is the only way to stop it while it waits.
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	for _, channel := range []string{chirpChannel, notificationChannel} {
+		if err := listener.Listen(channel); err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Error listening for notifications", "channel", channel, "error", err)
+			}
+			return
+		}
+	}
+
+	ticker := time.NewTicker(listenerPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			if n == nil {
+				continue
+			}
+
+			id, err := uuid.Parse(n.Extra)
+			if err != nil {
+				slog.Error("Error parsing notification", "channel", n.Channel, "payload", n.Extra, "error", err)
+				continue
+			}
+
+			switch n.Channel {
+			case chirpChannel:
+				chirp, err := cfg.db.GetChirp(ctx, id)
+				if err != nil {
+					slog.Error("Error getting published chirp", "chirp_id", id, "error", err)
+					continue
+				}
+				cfg.chirpStream.Publish(chirp)
+			case notificationChannel:
+				notification, err := cfg.db.GetNotification(ctx, id)
+				if err != nil {
+					slog.Error("Error getting notification", "notification_id", id, "error", err)
+					continue
+				}
+				cfg.notifications.Publish(notification)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds request IDs taken from clients, which end up
+	// in every log line of the request.
+	maxRequestIDLength = 128
+)
+
+type logContextKey struct{}
+
+// requestLog is shared by the middleware that logs a request and the
+// handlers beneath it, which fill in what only they know.
+type requestLog struct {
+	logger *slog.Logger
+	// route is the pattern that matched inside a mounted mux, such as
+	// "GET /chirps/{chirpID}" under /api/v1.
+	route string
+}
+
+// loggerFrom returns the logger for the request that ctx belongs to, which
+// adds the request ID to every message, or the default logger outside of a
+// request.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if entry, ok := ctx.Value(logContextKey{}).(*requestLog); ok {
+		return entry.logger
+	}
+	return slog.Default()
+}
+
+// logServerError logs the error behind a 5xx response. Clients only see msg,
+// so this is the only record of what went wrong.
+func logServerError(r *http.Request, msg string, err error) {
+	loggerFrom(r.Context()).Error(msg, "error", err)
+}
+
+// respondWithServerError logs err and responds with a 500 problem whose
+// detail is msg.
+func respondWithServerError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	logServerError(r, msg, err)
+	respondWithError(w, http.StatusInternalServerError, msg)
+}
+
+// validRequestID reports whether a client-supplied request ID is short and
+// made of characters that are safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.ContainsRune("-_.:/+=", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// middlewareRequestLog gives each request an ID, taken from X-Request-ID if
+// the client or a proxy sent a usable one, and echoes it in the response.
+// Once the request is done it logs the method, route, status, duration and,
+// for authenticated requests, the user.
+func (cfg *apiConfig) middlewareRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		logger := slog.Default().With("request_id", id)
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			logger = logger.With("trace_id", span.TraceID().String())
+		}
+		entry := &requestLog{logger: logger}
+		r = r.WithContext(context.WithValue(r.Context(), logContextKey{}, entry))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		route := unmatchedRoute
+		if entry.route != "" {
+			route = mountedRoute(strings.TrimSuffix(r.Pattern, "/"), entry.route)
+		} else if r.Pattern != "" {
+			route = r.Pattern
+		}
+		nameSpan(r, route)
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", rec.status),
+			slog.Float64("duration_ms", float64(time.Since(start))/float64(time.Millisecond)),
+		}
+		if userID, ok := cfg.tokenUserID(r); ok {
+			attrs = append(attrs, slog.String("user_id", userID.String()))
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		entry.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// logRoute wraps a mux mounted under a prefix with http.StripPrefix, so that
+// the request log names the route the mux matched rather than the prefix.
+func logRoute(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		if entry, ok := r.Context().Value(logContextKey{}).(*requestLog); ok {
+			entry.route = r.Pattern
+			if entry.route == "" {
+				entry.route = unmatchedRoute
+			}
+		}
+	})
+}
+
+// mountedRoute puts the prefix a mux is mounted under back into one of its
+// patterns, so "GET /chirps/{chirpID}" under /api/v1 becomes
+// "GET /api/v1/chirps/{chirpID}".
+func mountedRoute(prefix, pattern string) string {
+	if pattern == unmatchedRoute {
+		return pattern
+	}
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		return method + " " + prefix + path
+	}
+	return prefix + pattern
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/auth"
+)
+
+// captureLogs sends the default logger's output to a buffer for the rest of
+// the test and returns a function that decodes what was logged.
+func captureLogs(t *testing.T) func() []map[string]any {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return func() []map[string]any {
+		var records []map[string]any
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var record map[string]any
+			if err := dec.Decode(&record); err != nil {
+				t.Fatalf("log output is not JSON: %s", err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestMiddlewareRequestLog(t *testing.T) {
+	logs := captureLogs(t)
+
+	const secret = "0123456789abcdefghijklmnopqrstuv"
+	cfg := &apiConfig{tokenSecret: secret}
+	userID := uuid.New()
+	token, err := auth.MakeJWT(userID, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %s", err)
+	}
+
+	inner := http.NewServeMux()
+	inner.HandleFunc("GET /chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		respondWithServerError(w, r, "Failed to get chirp", errors.New("connection refused"))
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", logRoute(inner)))
+	handler := cfg.middlewareRequestLog(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chirps/123", nil)
+	req.Header.Set(requestIDHeader, "from-the-proxy")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(requestIDHeader); got != "from-the-proxy" {
+		t.Fatalf("%s is %q, expected the one the client sent", requestIDHeader, got)
+	}
+
+	records := logs()
+	if len(records) != 2 {
+		t.Fatalf("logged %d records, expected the error and the request: %v", len(records), records)
+	}
+	for _, record := range records {
+		if record["request_id"] != "from-the-proxy" {
+			t.Fatalf("record has request_id %v: %v", record["request_id"], record)
+		}
+	}
+	if records[0]["msg"] != "Failed to get chirp" || records[0]["error"] != "connection refused" {
+		t.Fatalf("the error was not logged: %v", records[0])
+	}
+
+	request := records[1]
+	want := map[string]any{
+		"level":   "ERROR",
+		"msg":     "request",
+		"method":  http.MethodGet,
+		"route":   "GET /api/v1/chirps/{chirpID}",
+		"status":  float64(http.StatusInternalServerError),
+		"user_id": userID.String(),
+	}
+	for key, value := range want {
+		if request[key] != value {
+			t.Fatalf("%s is %v, expected %v: %v", key, request[key], value, request)
+		}
+	}
+	if _, ok := request["duration_ms"]; !ok {
+		t.Fatalf("duration was not logged: %v", request)
+	}
+}
+
+func TestMiddlewareRequestLogAssignsID(t *testing.T) {
+	logs := captureLogs(t)
+
+	cfg := &apiConfig{}
+	handler := cfg.middlewareRequestLog(http.NewServeMux())
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set(requestIDHeader, "has spaces\nand a newline")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(requestIDHeader)
+	if _, err := uuid.Parse(id); err != nil {
+		t.Fatalf("%s is %q, expected a new UUID in place of the unusable one", requestIDHeader, id)
+	}
+
+	records := logs()
+	if len(records) != 1 {
+		t.Fatalf("logged %d records, expected 1: %v", len(records), records)
+	}
+	if records[0]["request_id"] != id || records[0]["route"] != unmatchedRoute || records[0]["level"] != "INFO" {
+		t.Fatalf("unexpected request record: %v", records[0])
+	}
+	if _, ok := records[0]["user_id"]; ok {
+		t.Fatalf("anonymous request logged a user: %v", records[0])
+	}
+}
+
+func TestRespondWithAuthError(t *testing.T) {
+	captureLogs(t)
+
+	for err, want := range map[error]int{
+		auth.ErrInvalidToken:             http.StatusUnauthorized,
+		auth.ErrInactiveUser:             http.StatusUnauthorized,
+		sql.ErrNoRows:                    http.StatusUnauthorized,
+		errors.New("connection refused"): http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		respondWithAuthError(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil), err)
+		if rec.Code != want {
+			t.Errorf("%v got %d, expected %d", err, rec.Code, want)
+		}
+	}
+}
@@ -1,68 +1,249 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
 
+	"github.com/debobrad579/chirpy/internal/config"
 	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/notifications"
+	"github.com/debobrad579/chirpy/internal/ratelimit"
+	"github.com/debobrad579/chirpy/internal/store"
+	"github.com/debobrad579/chirpy/internal/stream"
+	"github.com/debobrad579/chirpy/internal/webhooks"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             database.Queries
-	platform       string
-	tokenSecret    string
-	polkaKey       string
+	db            database.Queries
+	conn          *sql.DB
+	platform      string
+	tokenSecret   string
+	polkaKey      string
+	adminKey      string
+	chirpStream   *stream.Broker
+	notifications *notifications.Hub
+	rateLimits    ratelimit.Store
+	trustProxy    bool
+	metrics       *metrics
+	migrations    *goose.Provider
+	// webhookClient sends webhook deliveries. It refuses to connect to
+	// addresses that are not public.
+	webhookClient *http.Client
+
+	// store is what the user, chirp and token handlers use. It is the same
+	// as db unless Chirpy runs without PostgreSQL, in which case conn is nil
+	// and only storeRoutes are served.
+	store store.Store
+
+	// draining is set once the server has been told to stop, so that
+	// /readyz fails while requests are drained.
+	draining atomic.Bool
+	// shutdown is closed when the server starts shutting down, so that
+	// streaming handlers can end their responses.
+	shutdown chan struct{}
+	// upgraded tracks hijacked WebSocket connections, which the server does
+	// not wait for on shutdown.
+	upgraded sync.WaitGroup
+	workers  workerHealth
+
+	deactivationGracePeriod time.Duration
+	deletionPolicy          string
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
+		cfg.metrics.fileserverHits.Inc()
 		next.ServeHTTP(w, r)
 	})
 }
 
 func (cfg *apiConfig) resetHits() {
-	cfg.fileserverHits.Store(0)
+	cfg.metrics.resetFileserverHits()
 }
 
-const (
-	port         = "8080"
-	filepathRoot = "."
-)
+const filepathRoot = "."
+
+// routes returns the handler for everything the server serves.
+func (cfg *apiConfig) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/app/", http.StripPrefix("/app", cfg.middlewareMetricsInc(http.FileServer(http.Dir(filepathRoot)))))
+	routes := apiMux(cfg)
+	adminRoutes := adminMux(cfg)
+	var api, admin http.Handler = cfg.middlewareIdempotency(routes, cfg.middlewareRateLimit(routes)), adminRoutes
+	if cfg.conn == nil {
+		// Idempotency keys are kept in PostgreSQL, so they are not honoured
+		// without it.
+		api = middlewareStoreRoutes(routes, cfg.middlewareRateLimit(routes))
+		admin = middlewareStoreRoutes(adminRoutes, adminRoutes)
+	}
+	api = cfg.middlewareMetrics(routes, api)
+	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", logRoute(api)))
+	// Unversioned paths predate /api/v1 and are kept for existing clients.
+	mux.Handle("/api/", http.StripPrefix("/api", logRoute(api)))
+	mux.Handle("/admin/", http.StripPrefix("/admin", logRoute(admin)))
+	mux.Handle("GET /metrics", cfg.metrics.handler())
+	mux.HandleFunc("GET /livez", handleLivez)
+	mux.HandleFunc("GET /readyz", cfg.handleReadyz)
+	return mux
+}
+
+// fatal logs msg and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
 
 func main() {
 	godotenv.Load()
-	dbURL := os.Getenv("DB_URL")
-	db, err := sql.Open("postgres", dbURL)
+
+	conf, args, err := config.Load(os.Args[1:], os.LookupEnv)
 	if err != nil {
-		log.Fatal("Failed to open database")
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		// The configuration may not say how to log yet, so this goes to
+		// stderr as plain text with one problem per line.
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%s\n", err)
+		os.Exit(1)
 	}
 
-	mux := http.NewServeMux()
+	var level slog.Level
+	level.UnmarshalText([]byte(conf.LogLevel))
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	shutdownTracing, err := setupTracing(context.Background(), conf.TraceExporter)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
 
-	srv := &http.Server{
-		Handler: mux,
-		Addr:    ":" + port,
+	backend, err := openBackend(conf.DBURL)
+	if err != nil {
+		fatal("Failed to open database", "error", err)
+	}
+	// Without PostgreSQL, db is nil and only the store is available.
+	var db *sql.DB
+	if backend.postgres {
+		db = backend.db
+	} else if conf.RateLimitStore == rateLimitStorePostgres {
+		fatal("Invalid RATE_LIMIT_STORE", "error", "postgres needs DB_URL to be a PostgreSQL database")
 	}
 
+	rateLimits, err := newRateLimitStore(conf.RateLimitStore, db)
+	if err != nil {
+		fatal("Invalid RATE_LIMIT_STORE", "error", err)
+	}
+
+	queries := database.New(database.Traced(db))
+
 	cfg := &apiConfig{
-		db:          *database.New(db),
-		platform:    os.Getenv("PLATFORM"),
-		tokenSecret: os.Getenv("TOKEN_SECRET"),
-		polkaKey:    os.Getenv("POLKA_KEY"),
+		db:            *queries,
+		conn:          db,
+		platform:      conf.Platform,
+		tokenSecret:   conf.TokenSecret,
+		polkaKey:      conf.PolkaKey,
+		adminKey:      conf.AdminKey,
+		chirpStream:   stream.NewBroker(chirpReplaySize),
+		notifications: notifications.NewHub(),
+		rateLimits:    rateLimits,
+		trustProxy:    conf.TrustProxy,
+		metrics:       newMetrics(backend.db),
+		migrations:    backend.migrations,
+		webhookClient: webhooks.NewClient(deliveryTimeout),
+		store:         backend.store,
+		shutdown:      make(chan struct{}),
+
+		deactivationGracePeriod: conf.DeactivationGracePeriod,
+		deletionPolicy:          conf.DeletedUserChirps,
 	}
 
-	mux.Handle("/app/", http.StripPrefix("/app", cfg.middlewareMetricsInc(http.FileServer(http.Dir(filepathRoot)))))
-	mux.Handle("/api/", http.StripPrefix("/api", apiMux(cfg)))
-	mux.Handle("/admin/", http.StripPrefix("/admin", adminMux(cfg)))
+	if len(args) > 0 {
+		switch args[0] {
+		case "import":
+			if db == nil {
+				fatal("Command needs PostgreSQL", "command", args[0])
+			}
+			os.Exit(runImportCommand(cfg, args[1:]))
+		case "migrate":
+			if backend.migrations == nil {
+				fatal("Command needs a database", "command", args[0])
+			}
+			os.Exit(runMigrateCommand(backend.migrations, args[1:]))
+		default:
+			fatal("Unknown command", "command", args[0])
+		}
+	}
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(srv.ListenAndServe())
+	slog.Info("Effective configuration", "config", conf)
+
+	if migrations := backend.migrations; migrations != nil {
+		if conf.MigrateOnStart {
+			results, err := migrations.Up(context.Background())
+			for _, result := range results {
+				slog.Info("Applied migration", "migration", result.Source.Path, "duration", result.Duration.String())
+			}
+			if err != nil {
+				fatal("Failed to migrate", "error", err)
+			}
+		}
+		if err := checkSchema(context.Background(), migrations); err != nil {
+			fatal("Refusing to start", "error", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Workers keep running until the server has drained, since in-flight
+	// requests may still queue work for them.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runners := []func(context.Context){
+		cfg.runChirpPublisher,
+		cfg.runWebhookDeliverer,
+		func(ctx context.Context) { cfg.runListener(ctx, conf.DBURL) },
+		cfg.runUserPurger,
+		cfg.runExporter,
+		cfg.runRateLimitSweeper,
+		cfg.runIdempotencySweeper,
+	}
+	if db == nil {
+		// The other workers only have work queued in PostgreSQL.
+		slog.Warn("Running without PostgreSQL; only the core routes are served")
+		runners = []func(context.Context){cfg.runChirpPublisher, cfg.runRateLimitSweeper}
+	}
+	for _, run := range runners {
+		workers.Go(func() { run(workerCtx) })
+	}
+
+	slog.Info("Serving files", "root", filepathRoot)
+	err = cfg.serve(ctx, newServer(conf, middlewareTracing(cfg.middlewareRequestLog(cfg.routes()))), conf)
+
+	stopWorkers()
+	workers.Wait()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+
+	if err != nil {
+		fatal("Server failed", "error", err)
+	}
+	slog.Info("Server stopped")
 }
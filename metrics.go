@@ -0,0 +1,193 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/debobrad579/chirpy/internal/webhooks"
+)
+
+const (
+	metricsNamespace     = "chirpy"
+	fileserverHitsMetric = metricsNamespace + "_fileserver_hits_total"
+
+	loginSuccess = "success"
+	loginFailure = "failure"
+
+	chirpSourceAPI    = "api"
+	chirpSourceImport = "import"
+
+	// webhookRetrying is the delivery outcome for a failed attempt that
+	// will be retried.
+	webhookRetrying = "retrying"
+
+	// unmatchedRoute labels requests that no route handled, so that probing
+	// random paths cannot create new series.
+	unmatchedRoute = "unmatched"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	logins            *prometheus.CounterVec
+	chirpsCreated     *prometheus.CounterVec
+	webhookDeliveries *prometheus.CounterVec
+	fileserverHits    prometheus.Counter
+
+	// hitsAtReset holds the float64 bits of fileserverHits when the admin
+	// last reset it. Counters only go up, so the admin page shows the
+	// difference.
+	hitsAtReset atomic.Uint64
+}
+
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "API requests by route pattern and status code.",
+		}, []string{"route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "API request latency by route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result.",
+		}, []string{"result"}),
+		chirpsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "chirps_created_total",
+			Help:      "Chirps created, by whether they came from the API or an import.",
+		}, []string{"source"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Outbound webhook delivery attempts by outcome.",
+		}, []string{"outcome"}),
+		fileserverHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fileserverHitsMetric,
+			Help: "Requests to the static file server under /app.",
+		}),
+	}
+
+	for _, result := range []string{loginSuccess, loginFailure} {
+		m.logins.WithLabelValues(result)
+	}
+	for _, source := range []string{chirpSourceAPI, chirpSourceImport} {
+		m.chirpsCreated.WithLabelValues(source)
+	}
+	for _, outcome := range []string{webhooks.DeliveryDelivered, webhookRetrying, webhooks.DeliveryFailed} {
+		m.webhookDeliveries.WithLabelValues(outcome)
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.logins,
+		m.chirpsCreated,
+		m.webhookDeliveries,
+		m.fileserverHits,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "chirpy"))
+	}
+
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// counterValue sums every series of the named counter as gathered from the
+// registry.
+func (m *metrics) counterValue(name string) float64 {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return 0
+	}
+
+	var total float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue()
+		}
+	}
+	return total
+}
+
+func (m *metrics) fileserverHitsSinceReset() int {
+	return int(m.counterValue(fileserverHitsMetric) - math.Float64frombits(m.hitsAtReset.Load()))
+}
+
+func (m *metrics) resetFileserverHits() {
+	m.hitsAtReset.Store(math.Float64bits(m.counterValue(fileserverHitsMetric)))
+}
+
+// statusRecorder remembers the status code of a response. Unwrap lets
+// http.ResponseController and WebSocket upgrades reach the connection.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// middlewareMetrics records the count and latency of requests routed by mux,
+// labelled by route pattern rather than path so that IDs in paths do not
+// each get their own series.
+func (cfg *apiConfig) middlewareMetrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		if _, pattern := mux.Handler(r); pattern != "" {
+			route = pattern
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		status := strconv.Itoa(rec.status)
+		cfg.metrics.requests.WithLabelValues(route, status).Inc()
+		cfg.metrics.requestDuration.WithLabelValues(route, status).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareMetrics(t *testing.T) {
+	cfg := &apiConfig{metrics: newMetrics(nil)}
+	routes := apiMux(cfg)
+	handler := cfg.middlewareMetrics(routes, routes)
+
+	for _, path := range []string{"/healthz", "/healthz", "/chirps/not-a-uuid", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	families, err := cfg.metrics.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %s", err)
+	}
+
+	got := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "chirpy_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			got[labels["route"]+" "+labels["status"]] = metric.GetCounter().GetValue()
+		}
+	}
+
+	want := map[string]float64{
+		"GET /healthz 200":          2,
+		"GET /chirps/{chirpID} 400": 1,
+		unmatchedRoute + " 404":     1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("recorded %v, expected %v", got, want)
+	}
+	for series, n := range want {
+		if got[series] != n {
+			t.Fatalf("recorded %v, expected %v", got, want)
+		}
+	}
+}
+
+func TestFileserverHitsReset(t *testing.T) {
+	cfg := &apiConfig{metrics: newMetrics(nil)}
+	handler := cfg.middlewareMetricsInc(http.NotFoundHandler())
+
+	for range 3 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if hits := cfg.metrics.fileserverHitsSinceReset(); hits != 3 {
+		t.Fatalf("hits are %d, expected 3", hits)
+	}
+
+	cfg.resetHits()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if hits := cfg.metrics.fileserverHitsSinceReset(); hits != 1 {
+		t.Fatalf("hits are %d after a reset, expected 1", hits)
+	}
+}
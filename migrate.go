@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var migrationFiles embed.FS
+
+// newMigrator returns a goose provider for the migrations built into the
+// binary. Migrating up or down holds a PostgreSQL advisory lock, so instances
+// that start at the same time apply each migration once.
+func newMigrator(db *sql.DB) (*goose.Provider, error) {
+	schema, err := fs.Sub(migrationFiles, "sql/schema")
+	if err != nil {
+		return nil, err
+	}
+
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(goose.DialectPostgres, db, schema, goose.WithSessionLocker(locker))
+}
+
+// newSQLiteMigrator returns a goose provider for the SQLite backend's
+// migrations. A SQLite database has a single server, so no lock is taken.
+func newSQLiteMigrator(db *sql.DB) (*goose.Provider, error) {
+	schema, err := fs.Sub(migrationFiles, "sql/sqlite/schema")
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(goose.DialectSQLite3, db, schema)
+}
+
+// checkSchema returns an error if the database is missing migrations that
+// this build's queries depend on. A newer schema is accepted, so that an
+// older instance keeps running while a newer one is rolled out.
+func checkSchema(ctx context.Context, migrations *goose.Provider) error {
+	current, target, err := migrations.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current < target {
+		return fmt.Errorf("schema is at version %d, expected %d; run chirpy migrate up or set MIGRATE_ON_START", current, target)
+	}
+	return nil
+}
+
+func runMigrateCommand(migrations *goose.Provider, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: chirpy migrate up|down|status")
+		fmt.Fprintln(fs.Output(), "  up      apply every pending migration")
+		fmt.Fprintln(fs.Output(), "  down    roll back the newest applied migration")
+		fmt.Fprintln(fs.Output(), "  status  list the migrations and when they were applied")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+
+	switch fs.Arg(0) {
+	case "up":
+		results, err := migrations.Up(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to migrate up: %s\n", err)
+			return 1
+		}
+		if len(results) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		result, err := migrations.Down(ctx)
+		if result != nil {
+			fmt.Println(result)
+		}
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Println("No migrations to roll back")
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to migrate down: %s\n", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrations.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get migration status: %s\n", err)
+			return 1
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.State == goose.StateApplied {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-20s %s\n", applied, path.Base(status.Source.Path))
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	return 0
+}
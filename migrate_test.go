@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	db, err := sql.Open("postgres", "postgres://chirpy@127.0.0.1:1/chirpy?sslmode=disable")
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	defer db.Close()
+
+	migrations, err := newMigrator(db)
+	if err != nil {
+		t.Fatalf("newMigrator failed: %s", err)
+	}
+
+	files, err := os.ReadDir("sql/schema")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %s", err)
+	}
+
+	sources := migrations.ListSources()
+	if len(sources) != len(files) {
+		t.Fatalf("embedded %d migrations, but sql/schema has %d files", len(sources), len(files))
+	}
+	for i, source := range sources {
+		if source.Version != int64(i+1) {
+			t.Fatalf("migration %s has version %d, expected %d", source.Path, source.Version, i+1)
+		}
+	}
+}
+
+func TestEmbeddedSQLiteMigrations(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	defer db.Close()
+
+	migrations, err := newSQLiteMigrator(db)
+	if err != nil {
+		t.Fatalf("newSQLiteMigrator failed: %s", err)
+	}
+
+	files, err := os.ReadDir("sql/sqlite/schema")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %s", err)
+	}
+
+	if sources := migrations.ListSources(); len(sources) != len(files) {
+		t.Fatalf("embedded %d migrations, but sql/sqlite/schema has %d files", len(sources), len(files))
+	}
+}
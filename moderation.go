@@ -0,0 +1,36 @@
+package main
+
+const (
+	reportOpen      = "open"
+	reportDismissed = "dismissed"
+	reportActioned  = "actioned"
+)
+
+const (
+	actionDismiss         = "dismiss"
+	actionHideChirp       = "hide_chirp"
+	actionWarnUser        = "warn_user"
+	actionSuspendUser     = "suspend_user"
+	actionGrantModerator  = "grant_moderator"
+	actionRevokeModerator = "revoke_moderator"
+	actionExportUser      = "export_user"
+)
+
+const (
+	reportsPageSize  = 100
+	auditLogPageSize = 200
+	maxReportDetails = 1000
+)
+
+// reportActions are the actions a moderator can resolve a report with.
+var reportActions = []string{actionDismiss, actionHideChirp, actionWarnUser, actionSuspendUser}
+
+var reportReasons = []string{
+	"spam",
+	"harassment",
+	"hate",
+	"violence",
+	"sexual",
+	"misinformation",
+	"other",
+}
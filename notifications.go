@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/notifications"
+)
+
+const notificationsPageSize = 100
+
+func notifyMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	for _, email := range notifications.Mentions(chirp.Body) {
+		user, err := q.GetUserByEmail(ctx, email)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return err
+		}
+
+		if user.ID == chirp.UserID {
+			continue
+		}
+
+		blocked, err := q.IsBlocked(ctx, database.IsBlockedParams{BlockerID: user.ID, BlockedID: chirp.UserID})
+		if err != nil {
+			return err
+		}
+
+		muted, err := q.IsMuted(ctx, database.IsMutedParams{MuterID: user.ID, MutedID: chirp.UserID})
+		if err != nil {
+			return err
+		}
+
+		if blocked || muted {
+			continue
+		}
+
+		if _, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:  user.ID,
+			Type:    notifications.TypeMention,
+			ActorID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func notifyMessage(ctx context.Context, q *database.Queries, message database.Message) error {
+	members, err := q.GetConversationMembers(ctx, message.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.UserID == message.UserID || member.Muted {
+			continue
+		}
+
+		if _, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:         member.UserID,
+			Type:           notifications.TypeMessage,
+			ActorID:        uuid.NullUUID{UUID: message.UserID, Valid: true},
+			ConversationID: uuid.NullUUID{UUID: message.ConversationID, Valid: true},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
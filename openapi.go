@@ -0,0 +1,18 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec documents every route in apiMux and adminMux. It is maintained
+// by hand; openapi_test.go fails when it drifts from the registered routes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
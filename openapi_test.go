@@ -0,0 +1,347 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/importer"
+	"github.com/debobrad579/chirpy/internal/validate"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]map[string]any `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema map[string]any `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %s", err)
+	}
+	return doc
+}
+
+// registeredRoutes returns the patterns passed to mux.HandleFunc in the given
+// function, read from the source so that no route can be missed.
+func registeredRoutes(t *testing.T, file, funcName string) []string {
+	t.Helper()
+
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %s", file, err)
+	}
+
+	var routes []string
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != funcName {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				pattern, _ := strconv.Unquote(lit.Value)
+				routes = append(routes, pattern)
+			}
+			return true
+		})
+	}
+
+	if len(routes) == 0 {
+		t.Fatalf("found no routes in %s", funcName)
+	}
+	return routes
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	cfg := &apiConfig{}
+
+	muxes := []struct {
+		file, funcName, prefix string
+		mux                    *http.ServeMux
+	}{
+		{"api.go", "apiMux", "/api/v1", apiMux(cfg)},
+		{"admin.go", "adminMux", "/admin", adminMux(cfg)},
+	}
+
+	documented := map[string]bool{}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for _, m := range muxes {
+		for _, pattern := range registeredRoutes(t, m.file, m.funcName) {
+			method, path, _ := strings.Cut(pattern, " ")
+			route := method + " " + m.prefix + path
+			if !documented[route] {
+				t.Fatalf("%s is registered in %s but missing from openapi.json", route, m.funcName)
+			}
+			delete(documented, route)
+
+			// Check that a concrete request for the documented path really
+			// reaches the route.
+			concrete := path
+			for strings.Contains(concrete, "{") {
+				start := strings.Index(concrete, "{")
+				end := strings.Index(concrete, "}")
+				concrete = concrete[:start] + uuid.NewString() + concrete[end+1:]
+			}
+			_, matched := m.mux.Handler(httptest.NewRequest(method, concrete, nil))
+			if matched != pattern {
+				t.Fatalf("%s %s was routed to %q, expected %q", method, concrete, matched, pattern)
+			}
+		}
+	}
+
+	for route := range documented {
+		t.Fatalf("%s is documented in openapi.json but not registered", route)
+	}
+}
+
+func TestOpenAPIOperationsHaveSuccessResponse(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			ok := false
+			for code := range op.Responses {
+				if strings.HasPrefix(code, "2") || code == "101" {
+					ok = true
+				}
+			}
+			if !ok {
+				t.Fatalf("%s %s documents no success response", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIResponseShapes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	now := time.Now()
+
+	chirp := toChirpResponse(database.Chirp{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "hello", UserID: uuid.New(), PublishAt: sql.NullTime{Time: now, Valid: true}})
+	user := database.CreateUserRow{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Email: "a@example.com"}
+	report := toReportResponse(database.Report{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Reason: "spam", Status: reportOpen})
+	conversation := database.Conversation{ID: uuid.New(), CreatedAt: now, UpdatedAt: now}
+	message := database.Message{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "hi"}
+	notification := toNotificationResponse(database.Notification{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Type: "mention", ActorID: uuid.NullUUID{UUID: uuid.New(), Valid: true}})
+	webhook := toWebhookResponse(database.Webhook{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Url: "https://example.com", Secret: "secret", Events: []string{"chirp.created"}})
+	listedWebhook := toWebhookRowResponse(database.GetWebhookRow{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Url: "https://example.com", Events: []string{"chirp.created"}})
+	delivery := toWebhookDeliveryResponse(database.GetWebhookDeliveriesRow{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Event: "chirp.created", Payload: json.RawMessage(`{"event":"chirp.created"}`), Status: "failed", LastStatusCode: sql.NullInt32{Int32: 500, Valid: true}, LastError: sql.NullString{String: "timeout", Valid: true}})
+	action := database.ModerationAction{ID: uuid.New(), CreatedAt: now, Action: actionDismiss}
+	job := toExportJobResponse(database.GetExportJobRow{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Status: exportCompleted, CompletedAt: sql.NullTime{Time: now, Valid: true}, ExpiresAt: sql.NullTime{Time: now, Valid: true}})
+	result := importer.Result{Imported: 1, Errors: []importer.RowError{{Line: 2, Error: "Chirp is too long"}}}
+
+	// Responses built from named types. Handlers that respond with a local
+	// returnVals struct are covered by the schema alone.
+	responses := map[string]any{
+		"POST /api/v1/chirps":                                  chirp,
+		"GET /api/v1/chirps":                                   []chirpResponse{chirp},
+		"GET /api/v1/chirps/{chirpID}":                         chirp,
+		"POST /api/v1/chirps/import":                           result,
+		"POST /api/v1/chirps/{chirpID}/report":                 report,
+		"GET /api/v1/chirps/scheduled":                         []chirpResponse{chirp},
+		"PUT /api/v1/chirps/scheduled/{chirpID}":               chirp,
+		"POST /api/v1/conversations":                           conversation,
+		"GET /api/v1/conversations":                            []database.Conversation{conversation},
+		"GET /api/v1/conversations/{conversationID}/messages":  []database.Message{message},
+		"POST /api/v1/conversations/{conversationID}/messages": message,
+		"GET /api/v1/notifications":                            []notificationResponse{notification},
+		"POST /api/v1/users":                                   user,
+		"PUT /api/v1/users":                                    database.UpdateUserRow(user),
+		"GET /api/v1/blocks":                                   []database.Block{{BlockerID: uuid.New(), BlockedID: uuid.New(), CreatedAt: now}},
+		"GET /api/v1/mutes":                                    []database.Mute{{MuterID: uuid.New(), MutedID: uuid.New(), CreatedAt: now}},
+		"POST /api/v1/users/{userID}/report":                   report,
+		"POST /api/v1/users/me/export":                         job,
+		"GET /api/v1/users/me/exports/{exportID}":              job,
+		"POST /api/v1/webhooks":                                webhook,
+		"GET /api/v1/webhooks":                                 []webhookResponse{listedWebhook},
+		"GET /api/v1/webhooks/{webhookID}/deliveries":          []webhookDeliveryResponse{delivery},
+		"POST /admin/webhooks":                                 webhook,
+		"GET /admin/webhooks":                                  []webhookResponse{listedWebhook},
+		"GET /admin/webhooks/{webhookID}/deliveries":           []webhookDeliveryResponse{delivery},
+		"GET /admin/reports":                                   []reportResponse{report},
+		"POST /admin/reports/{reportID}/actions":               action,
+		"GET /admin/audit-log":                                 []database.ModerationAction{action},
+		"POST /admin/users/{userID}/export":                    job,
+		"GET /admin/exports/{exportID}":                        job,
+	}
+
+	for route, value := range responses {
+		method, path, _ := strings.Cut(route, " ")
+		op, ok := doc.Paths[path][strings.ToLower(method)]
+		if !ok {
+			t.Fatalf("%s is not documented", route)
+		}
+
+		var schema map[string]any
+		for code, response := range op.Responses {
+			if strings.HasPrefix(code, "2") {
+				if content, ok := response.Content["application/json"]; ok {
+					schema = content.Schema
+					break
+				}
+			}
+		}
+		if schema == nil {
+			t.Fatalf("%s documents no JSON success response", route)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("failed to encode %s response: %s", route, err)
+		}
+		var decoded any
+		json.Unmarshal(data, &decoded)
+
+		if err := matchSchema(doc, decoded, schema, "response"); err != nil {
+			t.Fatalf("%s: %s", route, err)
+		}
+	}
+}
+
+// matchSchema checks value against the subset of JSON Schema used in
+// openapi.json: $ref, type, properties, required, additionalProperties and
+// items.
+func matchSchema(doc openAPIDoc, value any, schema map[string]any, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target, ok := doc.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, ref)
+		}
+		return matchSchema(doc, value, target, at)
+	}
+
+	var types []string
+	switch typ := schema["type"].(type) {
+	case string:
+		types = []string{typ}
+	case []any:
+		for _, t := range typ {
+			types = append(types, t.(string))
+		}
+	}
+	if len(types) > 0 && !slices.Contains(types, jsonType(value)) && !(jsonType(value) == "integer" && slices.Contains(types, "number")) {
+		return fmt.Errorf("%s is %s, expected %v", at, jsonType(value), types)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					return fmt.Errorf("%s is missing %s", at, name)
+				}
+			}
+		}
+		for name, field := range v {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s has undocumented field %s", at, name)
+				}
+				continue
+			}
+			if err := matchSchema(doc, field, property, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := matchSchema(doc, item, items, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func TestOpenAPIProblemShape(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	p := validate.NewProblem(http.StatusBadRequest, "Request body has invalid fields")
+	p.Errors = []validate.FieldError{{Pointer: "#/email", Detail: "Must be a valid email address"}}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to encode problem: %s", err)
+	}
+	var decoded any
+	json.Unmarshal(data, &decoded)
+
+	if err := matchSchema(doc, decoded, map[string]any{"$ref": "#/components/schemas/Problem"}, "problem"); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			for code, response := range op.Responses {
+				if _, ok := response.Content["application/json"]; ok && code[0] >= '4' {
+					t.Fatalf("%s %s documents a %s response as application/json instead of a problem", strings.ToUpper(method), path, code)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/webhooks"
+)
+
+const (
+	deliveryInterval  = 10 * time.Second
+	deliveryBatchSize = 50
+	deliveryTimeout   = 10 * time.Second
+	deliveryLogLimit  = 100
+	// deliveryLease is how long a claimed batch is left to one instance. It
+	// must outlast sending a whole batch, one delivery after another.
+	deliveryLease = deliveryBatchSize*deliveryTimeout + time.Minute
+)
+
+// enqueueWebhookEvent writes a delivery to the outbox for every webhook
+// subscribed to event that may see it: the admin webhooks, and those of
+// actorID, the user the event is about. Pass a transaction-scoped Queries so
+// the event is only recorded if the change that caused it is committed.
+func enqueueWebhookEvent(ctx context.Context, q *database.Queries, event string, actorID uuid.UUID, data any) error {
+	payload, err := webhooks.NewPayload(event, data)
+	if err != nil {
+		return err
+	}
+
+	tp := traceparent(ctx)
+	return q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		Event:       event,
+		Payload:     payload,
+		Traceparent: sql.NullString{String: tp, Valid: tp != ""},
+		ActorID:     actorID,
+	})
+}
+
+// deliverWebhooks claims a batch of due deliveries and sends them. Claiming
+// pushes next_attempt_at past deliveryLease, so the row locks are only held
+// for that one statement and other instances skip the batch while it is sent.
+// A delivery whose outcome is never written is retried once the lease ends.
+// Leases and retries are timed from the database's NOW(), which
+// next_attempt_at is compared with.
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context) (int, error) {
+	deliveries, err := cfg.db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseSeconds: deliveryLease.Seconds(),
+		BatchSize:    deliveryBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	outcomes := map[string]int{}
+	for _, delivery := range deliveries {
+		status, err := deliverWebhook(ctx, cfg.webhookClient, delivery)
+
+		params := database.UpdateWebhookDeliveryParams{
+			ID:       delivery.ID,
+			Status:   webhooks.DeliveryDelivered,
+			Attempts: delivery.Attempts + 1,
+		}
+
+		if status != 0 {
+			params.LastStatusCode = sql.NullInt32{Int32: int32(status), Valid: true}
+		}
+
+		if err != nil {
+			params.LastError = sql.NullString{String: err.Error(), Valid: true}
+			if params.Attempts >= webhooks.MaxAttempts {
+				params.Status = webhooks.DeliveryFailed
+			} else {
+				params.Status = webhooks.DeliveryPending
+				params.RetryAfterSeconds = webhooks.Backoff(int(params.Attempts)).Seconds()
+			}
+		}
+
+		if err := cfg.db.UpdateWebhookDelivery(ctx, params); err != nil {
+			return 0, err
+		}
+		if params.Status == webhooks.DeliveryPending {
+			outcomes[webhookRetrying]++
+		} else {
+			outcomes[params.Status]++
+		}
+	}
+
+	for outcome, n := range outcomes {
+		cfg.metrics.webhookDeliveries.WithLabelValues(outcome).Add(float64(n))
+	}
+
+	return len(deliveries), nil
+}
+
+// deliverWebhook sends a delivery inside a span that continues the trace of
+// the request that queued it, and passes the trace on to the receiver.
+func deliverWebhook(ctx context.Context, client *http.Client, delivery database.ClaimWebhookDeliveriesRow) (int, error) {
+	if delivery.Traceparent.Valid {
+		ctx = withTraceparent(ctx, delivery.Traceparent.String)
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "deliver webhook",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.event", delivery.Event),
+			attribute.String("webhook.delivery_id", delivery.ID.String()),
+			attribute.Int("webhook.attempt", int(delivery.Attempts)+1),
+		),
+	)
+	defer span.End()
+
+	status, err := webhooks.Deliver(ctx, client, delivery.Url, delivery.Secret, delivery.ID.String(), delivery.Event, delivery.Payload)
+	if status != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return status, err
+}
+
+func (cfg *apiConfig) runWebhookDeliverer(ctx context.Context) {
+	cfg.runWorker(ctx, "webhook deliverer", deliveryInterval, deliveryBatchSize, cfg.deliverWebhooks)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/webhooks"
+)
+
+const (
+	publishInterval  = 30 * time.Second
+	publishBatchSize = 100
+)
+
+// publishDueChirps publishes scheduled chirps whose publish_at has passed.
+// Rows are locked with SKIP LOCKED, so several Chirpy instances can run the
+// publisher at once without publishing the same chirp twice.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) (int, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTracedTx(tx)
+
+	chirps, err := qtx.GetDueChirps(ctx, publishBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, chirp := range chirps {
+		if err := qtx.PublishChirp(ctx, chirp.ID); err != nil {
+			return 0, err
+		}
+
+		chirp.IsPublished = true
+		chirp.CreatedAt = chirp.PublishAt.Time
+		if err := enqueueWebhookEvent(ctx, qtx, webhooks.EventChirpCreated, chirp.UserID, toChirpResponse(chirp)); err != nil {
+			return 0, err
+		}
+
+		if err := notifyMentions(ctx, qtx, chirp); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(chirps), tx.Commit()
+}
+
+// publishDueStoreChirps publishes due chirps without PostgreSQL, which has
+// the only webhooks and notifications, so it does nothing else. Only one
+// Chirpy instance can use a memory: or sqlite: store, so nothing else
+// publishes at the same time.
+func (cfg *apiConfig) publishDueStoreChirps(ctx context.Context) (int, error) {
+	chirps, err := cfg.store.GetDueChirps(ctx, publishBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, chirp := range chirps {
+		if err := cfg.store.PublishChirp(ctx, chirp.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(chirps), nil
+}
+
+func (cfg *apiConfig) runChirpPublisher(ctx context.Context) {
+	work := cfg.publishDueChirps
+	if cfg.conn == nil {
+		work = cfg.publishDueStoreChirps
+	}
+	cfg.runWorker(ctx, "chirp publisher", publishInterval, publishBatchSize, work)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/ratelimit"
+)
+
+const (
+	rateLimitStoreMemory   = "memory"
+	rateLimitStorePostgres = "postgres"
+
+	rateLimitSweepInterval = 10 * time.Minute
+	rateLimitSweepAge      = 24 * time.Hour
+)
+
+type routeLimit struct {
+	policy ratelimit.Policy
+	// perUser limits authenticated requests by user ID. Anonymous requests,
+	// and every request to routes without perUser, are limited by client IP.
+	perUser bool
+}
+
+// routeLimits maps apiMux patterns to their rate limits. Routes without an
+// entry are not limited.
+var routeLimits = map[string]routeLimit{
+	"POST /users":                   {ratelimit.Policy{Name: "signup", Limit: 5, Window: time.Hour}, false},
+	"POST /login":                   {ratelimit.Policy{Name: "login", Limit: 10, Window: time.Minute}, false},
+	"POST /refresh":                 {ratelimit.Policy{Name: "refresh", Limit: 30, Window: time.Minute}, false},
+	"POST /chirps":                  {ratelimit.Policy{Name: "chirps", Limit: 30, Window: time.Minute}, true},
+	"POST /chirps/import":           {ratelimit.Policy{Name: "import", Limit: 10, Window: time.Hour}, true},
+	"POST /chirps/{chirpID}/report": {ratelimit.Policy{Name: "reports", Limit: 20, Window: time.Hour}, true},
+	"POST /users/{userID}/report":   {ratelimit.Policy{Name: "reports", Limit: 20, Window: time.Hour}, true},
+	"POST /conversations":           {ratelimit.Policy{Name: "conversations", Limit: 20, Window: time.Hour}, true},
+	"POST /conversations/{conversationID}/messages": {ratelimit.Policy{Name: "messages", Limit: 60, Window: time.Minute}, true},
+	"POST /users/me/export":                         {ratelimit.Policy{Name: "exports", Limit: 3, Window: 24 * time.Hour}, true},
+	"POST /webhooks":                                {ratelimit.Policy{Name: "webhooks", Limit: 10, Window: time.Hour}, true},
+}
+
+func newRateLimitStore(kind string, conn *sql.DB) (ratelimit.Store, error) {
+	switch kind {
+	case "", rateLimitStoreMemory:
+		return ratelimit.NewMemoryStore(), nil
+	case rateLimitStorePostgres:
+		return ratelimit.NewPostgresStore(conn), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+// clientKey identifies who sent a request. Only the signature of the access
+// token is checked here; the handler still authenticates the user properly.
+func (cfg *apiConfig) clientKey(r *http.Request, perUser bool) string {
+	if perUser {
+		if userID, ok := cfg.tokenUserID(r); ok {
+			return "user:" + userID.String()
+		}
+	}
+	return "ip:" + ratelimit.ClientIP(r, cfg.trustProxy)
+}
+
+// middlewareRateLimit applies routeLimits to requests routed by mux. If the
+// store fails the request is let through, so an outage of the store does not
+// take the API down with it.
+func (cfg *apiConfig) middlewareRateLimit(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		limit, ok := routeLimits[pattern]
+		if !ok {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		key := limit.policy.Name + ":" + cfg.clientKey(r, limit.perUser)
+		result, err := cfg.rateLimits.Take(r.Context(), key, limit.policy, time.Now())
+		if err != nil {
+			loggerFrom(r.Context()).Error("Error checking rate limit", "key", key, "error", err)
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		ratelimit.SetHeaders(w.Header(), limit.policy, result)
+		if !result.Allowed {
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (cfg *apiConfig) runRateLimitSweeper(ctx context.Context) {
+	cfg.runWorker(ctx, "rate limit sweeper", rateLimitSweepInterval, 1, func(ctx context.Context) (int, error) {
+		_, err := cfg.rateLimits.Sweep(ctx, time.Now().Add(-rateLimitSweepAge))
+		return 0, err
+	})
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+// The API responds with these rather than the database models, whose
+// sql.Null* fields would be encoded as objects like {"Time": ..., "Valid": ...}.
+// Missing values are null instead.
+
+type chirpResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Body        string     `json:"body"`
+	UserID      uuid.UUID  `json:"user_id"`
+	PublishAt   *time.Time `json:"publish_at"`
+	IsPublished bool       `json:"is_published"`
+	HiddenAt    *time.Time `json:"hidden_at"`
+}
+
+type conversationMemberResponse struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastReadAt     *time.Time `json:"last_read_at"`
+	Muted          bool       `json:"muted"`
+}
+
+type exportJobResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UserID      uuid.UUID  `json:"user_id"`
+	RequestedBy *uuid.UUID `json:"requested_by"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type notificationResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Type           string     `json:"type"`
+	ActorID        *uuid.UUID `json:"actor_id"`
+	ChirpID        *uuid.UUID `json:"chirp_id"`
+	ReadAt         *time.Time `json:"read_at"`
+	ConversationID *uuid.UUID `json:"conversation_id"`
+}
+
+type reportResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ReporterID     uuid.UUID  `json:"reporter_id"`
+	ReportedUserID uuid.UUID  `json:"reported_user_id"`
+	ChirpID        *uuid.UUID `json:"chirp_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ResolvedBy     *uuid.UUID `json:"resolved_by"`
+}
+
+// webhookResponse only carries the secret when the webhook is created.
+type webhookResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UserID    *uuid.UUID `json:"user_id"`
+	Url       string     `json:"url"`
+	Secret    string     `json:"secret,omitempty"`
+	Events    []string   `json:"events"`
+}
+
+type webhookDeliveryResponse struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int32          `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+}
+
+func toChirpResponse(chirp database.Chirp) chirpResponse {
+	return chirpResponse{
+		ID:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		UserID:      chirp.UserID,
+		PublishAt:   nullTime(chirp.PublishAt),
+		IsPublished: chirp.IsPublished,
+		HiddenAt:    nullTime(chirp.HiddenAt),
+	}
+}
+
+func toConversationMemberResponse(member database.ConversationMember) conversationMemberResponse {
+	return conversationMemberResponse{
+		ConversationID: member.ConversationID,
+		UserID:         member.UserID,
+		CreatedAt:      member.CreatedAt,
+		UpdatedAt:      member.UpdatedAt,
+		LastReadAt:     nullTime(member.LastReadAt),
+		Muted:          member.Muted,
+	}
+}
+
+func toExportJobResponse(job database.GetExportJobRow) exportJobResponse {
+	return exportJobResponse{
+		ID:          job.ID,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		UserID:      job.UserID,
+		RequestedBy: nullUUID(job.RequestedBy),
+		Status:      job.Status,
+		Error:       nullString(job.Error),
+		CompletedAt: nullTime(job.CompletedAt),
+		ExpiresAt:   nullTime(job.ExpiresAt),
+	}
+}
+
+func toNotificationResponse(notification database.Notification) notificationResponse {
+	return notificationResponse{
+		ID:             notification.ID,
+		CreatedAt:      notification.CreatedAt,
+		UpdatedAt:      notification.UpdatedAt,
+		UserID:         notification.UserID,
+		Type:           notification.Type,
+		ActorID:        nullUUID(notification.ActorID),
+		ChirpID:        nullUUID(notification.ChirpID),
+		ReadAt:         nullTime(notification.ReadAt),
+		ConversationID: nullUUID(notification.ConversationID),
+	}
+}
+
+func toReportResponse(report database.Report) reportResponse {
+	return reportResponse{
+		ID:             report.ID,
+		CreatedAt:      report.CreatedAt,
+		UpdatedAt:      report.UpdatedAt,
+		ReporterID:     report.ReporterID,
+		ReportedUserID: report.ReportedUserID,
+		ChirpID:        nullUUID(report.ChirpID),
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Status:         report.Status,
+		ResolvedAt:     nullTime(report.ResolvedAt),
+		ResolvedBy:     nullUUID(report.ResolvedBy),
+	}
+}
+
+func toWebhookResponse(webhook database.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        webhook.ID,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+		UserID:    nullUUID(webhook.UserID),
+		Url:       webhook.Url,
+		Secret:    webhook.Secret,
+		Events:    webhook.Events,
+	}
+}
+
+// toWebhookRowResponse converts a webhook read without its secret. The rows
+// of GetWebhooksForUser and GetAdminWebhooks convert to GetWebhookRow.
+func toWebhookRowResponse(webhook database.GetWebhookRow) webhookResponse {
+	return toWebhookResponse(database.Webhook{
+		ID:        webhook.ID,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+		UserID:    webhook.UserID,
+		Url:       webhook.Url,
+		Events:    webhook.Events,
+	})
+}
+
+func toWebhookDeliveryResponse(delivery database.GetWebhookDeliveriesRow) webhookDeliveryResponse {
+	return webhookDeliveryResponse{
+		ID:             delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		WebhookID:      delivery.WebhookID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: nullInt32(delivery.LastStatusCode),
+		LastError:      nullString(delivery.LastError),
+	}
+}
+
+// toResponses converts each of items, keeping a nil slice nil so that it is
+// still encoded as null.
+func toResponses[T, U any](items []T, fn func(T) U) []U {
+	if items == nil {
+		return nil
+	}
+	responses := make([]U, len(items))
+	for i, item := range items {
+		responses[i] = fn(item)
+	}
+	return responses
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+func nullUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/debobrad579/chirpy/internal/config"
+	"github.com/debobrad579/chirpy/internal/tlscert"
+)
+
+const certReloadInterval = time.Minute
+
+func newServer(conf *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(conf.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		ReadTimeout:       conf.ReadTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+	}
+}
+
+// disableTimeouts lifts the server's read and write timeouts for a response
+// that streams for as long as the client stays connected.
+func disableTimeouts(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	for _, err := range []error{rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{})} {
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.Error("Error disabling timeouts", "error", err)
+		}
+	}
+}
+
+// extendTimeouts gives a response that legitimately takes long, such as a
+// large upload, d from now to read the request and write the response,
+// instead of the server's read and write timeouts.
+func extendTimeouts(w http.ResponseWriter, d time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(d)
+	for _, err := range []error{rc.SetReadDeadline(deadline), rc.SetWriteDeadline(deadline)} {
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.Error("Error extending timeouts", "error", err)
+		}
+	}
+}
+
+// serve runs srv until ctx is cancelled and then shuts it down gracefully.
+// /readyz fails from then on, and the server keeps serving for
+// conf.ShutdownDelay so that load balancers can stop sending it requests.
+// Shutting down then stops accepting connections, tells streaming handlers to
+// finish through cfg.shutdown, and waits up to conf.ShutdownTimeout for
+// in-flight requests and upgraded connections to complete.
+func (cfg *apiConfig) serve(ctx context.Context, srv *http.Server, conf *config.Config) error {
+	srv.RegisterOnShutdown(func() {
+		close(cfg.shutdown)
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		if conf.TLSCertFile == "" {
+			slog.Info("Serving HTTP", "port", conf.Port)
+			errc <- srv.ListenAndServe()
+			return
+		}
+
+		reloader, err := tlscert.NewReloader(conf.TLSCertFile, conf.TLSKeyFile)
+		if err != nil {
+			errc <- fmt.Errorf("failed to load TLS certificate: %w", err)
+			return
+		}
+		go reloader.Watch(ctx, certReloadInterval)
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+		slog.Info("Serving HTTPS", "port", conf.Port)
+		errc <- srv.ListenAndServeTLS("", "")
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	cfg.draining.Store(true)
+	if conf.ShutdownDelay > 0 {
+		slog.Info("Draining before shutdown", "delay", conf.ShutdownDelay.String())
+		select {
+		case err := <-errc:
+			return err
+		case <-time.After(conf.ShutdownDelay):
+		}
+	}
+
+	slog.Info("Shutting down, waiting for requests to finish", "timeout", conf.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+
+	done := make(chan struct{})
+	go func() {
+		cfg.upgraded.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	return nil
+}
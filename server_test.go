@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+
+	"github.com/debobrad579/chirpy/internal/config"
+	"github.com/debobrad579/chirpy/internal/database"
+	"github.com/debobrad579/chirpy/internal/notifications"
+)
+
+// TestWebSocketOutlivesServerTimeouts serves the API through newServer, whose
+// read and write deadlines would otherwise end a hijacked connection.
+func TestWebSocketOutlivesServerTimeouts(t *testing.T) {
+	s := newBackendTestServer(t, memoryDBURL)
+	u := s.signUp(t)
+
+	const timeout = 100 * time.Millisecond
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = newServer(&config.Config{
+		ReadHeaderTimeout: timeout,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout,
+		IdleTimeout:       timeout,
+	}, apiMux(s.cfg))
+	srv.Start()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, srv.URL+"/ws", &websocket.DialOptions{
+		HTTPClient: srv.Client(),
+		HTTPHeader: http.Header{"Authorization": {u.auth()}},
+	})
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer conn.CloseNow()
+
+	received := make(chan notificationResponse, 1)
+	go func() {
+		var n notificationResponse
+		if err := wsjson.Read(ctx, conn, &n); err == nil {
+			received <- n
+		}
+	}()
+
+	time.Sleep(5 * timeout)
+
+	sent := database.Notification{ID: uuid.New(), UserID: u.ID, Type: notifications.TypeMention}
+	for {
+		s.cfg.notifications.Publish(sent)
+		select {
+		case n := <-received:
+			if n.ID != sent.ID {
+				t.Fatalf("received notification %s, expected %s", n.ID, sent.ID)
+			}
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		case <-ctx.Done():
+			t.Fatal("no notification was received after the server's timeouts")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// TestResponseRecorderExtendsTimeouts checks that a handler behind the
+// idempotency middleware can still extend the server's write timeout.
+func TestResponseRecorderExtendsTimeouts(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = newServer(&config.Config{
+		ReadHeaderTimeout: timeout,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout,
+		IdleTimeout:       timeout,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{ResponseWriter: w}
+		extendTimeouts(rec, time.Minute)
+		time.Sleep(3 * timeout)
+		rec.Write([]byte("OK"))
+	}))
+	srv.Start()
+	defer srv.Close()
+
+	res, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET failed after the write timeout: %s", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil || string(body) != "OK" {
+		t.Fatalf("response body is %q, %v", body, err)
+	}
+}
@@ -0,0 +1,91 @@
+-- name: CreateBlock :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+    VALUES ($1, $2, NOW())
+ON CONFLICT
+    DO NOTHING;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1
+    AND blocked_id = $2;
+
+-- name: GetBlocks :many
+SELECT
+    *
+FROM
+    blocks
+WHERE
+    blocker_id = $1
+ORDER BY
+    created_at DESC;
+
+-- name: IsBlocked :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            blocks
+        WHERE
+            blocker_id = $1
+            AND blocked_id = $2);
+
+-- name: BlockExistsBetween :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            blocks
+        WHERE (blocker_id = @user_id::uuid
+            AND blocked_id = @other_user_id::uuid)
+            OR (blocker_id = @other_user_id::uuid
+                AND blocked_id = @user_id::uuid));
+
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+    VALUES ($1, $2, NOW())
+ON CONFLICT
+    DO NOTHING;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1
+    AND muted_id = $2;
+
+-- name: GetMutes :many
+SELECT
+    *
+FROM
+    mutes
+WHERE
+    muter_id = $1
+ORDER BY
+    created_at DESC;
+
+-- name: IsMuted :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            mutes
+        WHERE
+            muter_id = $1
+            AND muted_id = $2);
+
+-- name: GetHiddenAuthors :many
+SELECT
+    blocker_id AS author_id
+FROM
+    blocks
+WHERE
+    blocked_id = $1
+UNION
+SELECT
+    muted_id AS author_id
+FROM
+    mutes
+WHERE
+    muter_id = $1;
+
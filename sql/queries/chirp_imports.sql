@@ -0,0 +1,19 @@
+-- name: ClaimChirpImport :execrows
+INSERT INTO chirp_imports (user_id, external_id, created_at)
+    VALUES ($1, $2, NOW())
+ON CONFLICT
+    DO NOTHING;
+
+-- name: MarkChirpImport :exec
+SELECT
+    set_config('chirpy.importing', 'on', TRUE);
+
+-- name: SetChirpImportChirp :exec
+UPDATE
+    chirp_imports
+SET
+    chirp_id = $3
+WHERE
+    user_id = $1
+    AND external_id = $2;
+
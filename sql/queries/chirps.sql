@@ -1,6 +1,12 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, publish_at, is_published)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, $3, $4)
+RETURNING
+    *;
+
+-- name: CreateImportedChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, is_published)
+    VALUES (gen_random_uuid (), $1, $1, $2, $3, TRUE)
 RETURNING
     *;
 
@@ -9,11 +15,30 @@ SELECT
     *
 FROM
     chirps
+WHERE
+    is_published
+    AND hidden_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            blocks
+        WHERE
+            blocks.blocker_id = chirps.user_id
+            AND blocks.blocked_id = @viewer_id::uuid)
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            mutes
+        WHERE
+            mutes.muter_id = @viewer_id::uuid
+            AND mutes.muted_id = chirps.user_id)
 ORDER BY
-    CASE WHEN $1 = 'asc' THEN
+    CASE WHEN @sort::text = 'asc' THEN
         created_at
     END ASC,
-    CASE WHEN $1 = 'desc' THEN
+    CASE WHEN @sort::text = 'desc' THEN
         created_at
     END DESC;
 
@@ -23,12 +48,30 @@ SELECT
 FROM
     chirps
 WHERE
-    user_id = $1
+    user_id = @user_id
+    AND is_published
+    AND hidden_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            blocks
+        WHERE
+            blocks.blocker_id = chirps.user_id
+            AND blocks.blocked_id = @viewer_id::uuid)
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            mutes
+        WHERE
+            mutes.muter_id = @viewer_id::uuid
+            AND mutes.muted_id = chirps.user_id)
 ORDER BY
-    CASE WHEN $2 = 'asc' THEN
+    CASE WHEN @sort::text = 'asc' THEN
         created_at
     END ASC,
-    CASE WHEN $2 = 'desc' THEN
+    CASE WHEN @sort::text = 'desc' THEN
         created_at
     END DESC;
 
@@ -38,9 +81,90 @@ SELECT
 FROM
     chirps
 WHERE
-    id = $1;
+    id = $1
+    AND is_published;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id = $1;
 
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = $1
+    AND NOT is_published;
+
+-- name: GetScheduledChirps :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    user_id = $1
+    AND NOT is_published
+ORDER BY
+    publish_at ASC;
+
+-- name: GetScheduledChirp :one
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    id = $1
+    AND NOT is_published;
+
+-- name: UpdateScheduledChirp :one
+UPDATE
+    chirps
+SET
+    body = $2,
+    publish_at = $3,
+    updated_at = NOW()
+WHERE
+    id = $1
+    AND NOT is_published
+RETURNING
+    *;
+
+-- name: GetDueChirps :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    NOT is_published
+    AND publish_at <= NOW()
+ORDER BY
+    publish_at ASC
+LIMIT $1
+FOR UPDATE
+    SKIP LOCKED;
+
+-- name: PublishChirp :exec
+UPDATE
+    chirps
+SET
+    is_published = TRUE,
+    created_at = publish_at,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: HideChirp :exec
+UPDATE
+    chirps
+SET
+    hidden_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: GetAllChirpsForUser :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    user_id = $1
+ORDER BY
+    created_at ASC;
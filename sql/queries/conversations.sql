@@ -0,0 +1,123 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at)
+    VALUES (gen_random_uuid (), NOW(), NOW())
+RETURNING
+    *;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, created_at, updated_at, muted)
+    VALUES ($1, $2, NOW(), NOW(), FALSE);
+
+-- name: GetDirectConversation :one
+SELECT
+    conversations.*
+FROM
+    conversation_members AS mine
+    JOIN conversation_members AS theirs ON theirs.conversation_id = mine.conversation_id
+    JOIN conversations ON conversations.id = mine.conversation_id
+WHERE
+    mine.user_id = @user_id::uuid
+    AND theirs.user_id = @other_user_id::uuid
+    AND (
+        SELECT
+            COUNT(*)
+        FROM
+            conversation_members
+        WHERE
+            conversation_members.conversation_id = mine.conversation_id) = 2
+LIMIT 1;
+
+-- name: GetConversation :one
+SELECT
+    *
+FROM
+    conversations
+WHERE
+    id = $1;
+
+-- name: GetConversationsForUser :many
+SELECT
+    conversations.*
+FROM
+    conversations
+    JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE
+    conversation_members.user_id = $1
+ORDER BY
+    conversations.updated_at DESC;
+
+-- name: GetConversationMember :one
+SELECT
+    *
+FROM
+    conversation_members
+WHERE
+    conversation_id = $1
+    AND user_id = $2;
+
+-- name: GetConversationMembers :many
+SELECT
+    *
+FROM
+    conversation_members
+WHERE
+    conversation_id = $1
+ORDER BY
+    created_at ASC;
+
+-- name: MarkConversationRead :exec
+UPDATE
+    conversation_members
+SET
+    last_read_at = NOW(),
+    updated_at = NOW()
+WHERE
+    conversation_id = $1
+    AND user_id = $2;
+
+-- name: SetConversationMuted :exec
+UPDATE
+    conversation_members
+SET
+    muted = $3,
+    updated_at = NOW()
+WHERE
+    conversation_id = $1
+    AND user_id = $2;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, updated_at, conversation_id, user_id, body)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, $3)
+RETURNING
+    *;
+
+-- name: TouchConversation :exec
+UPDATE
+    conversations
+SET
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: GetMessages :many
+SELECT
+    *
+FROM
+    messages
+WHERE
+    conversation_id = @conversation_id
+    AND created_at < COALESCE(sqlc.narg('before')::timestamp, NOW())
+ORDER BY
+    created_at DESC
+LIMIT @page_size;
+
+-- name: GetMessagesSentByUser :many
+SELECT
+    *
+FROM
+    messages
+WHERE
+    user_id = $1
+ORDER BY
+    created_at ASC;
+
@@ -0,0 +1,82 @@
+-- name: CreateExportJob :one
+INSERT INTO export_jobs (id, created_at, updated_at, user_id, requested_by, status)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, 'pending')
+RETURNING
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    requested_by,
+    status,
+    error,
+    completed_at,
+    expires_at;
+
+-- name: GetExportJob :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    requested_by,
+    status,
+    error,
+    completed_at,
+    expires_at
+FROM
+    export_jobs
+WHERE
+    id = $1;
+
+-- name: GetExportArchive :one
+SELECT
+    archive
+FROM
+    export_jobs
+WHERE
+    id = $1
+    AND status = 'completed'
+    AND expires_at > NOW();
+
+-- name: ClaimExportJob :one
+SELECT
+    id,
+    user_id
+FROM
+    export_jobs
+WHERE
+    status = 'pending'
+ORDER BY
+    created_at ASC
+LIMIT 1
+FOR UPDATE
+    SKIP LOCKED;
+
+-- name: CompleteExportJob :exec
+UPDATE
+    export_jobs
+SET
+    status = 'completed',
+    archive = $2,
+    completed_at = NOW(),
+    expires_at = $3,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: FailExportJob :exec
+UPDATE
+    export_jobs
+SET
+    status = 'failed',
+    error = $2,
+    completed_at = NOW(),
+    expires_at = $3,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: DeleteExpiredExportJobs :exec
+DELETE FROM export_jobs
+WHERE expires_at <= NOW();
+
@@ -0,0 +1,44 @@
+-- name: CreateIdempotencyKey :execrows
+INSERT INTO idempotency_keys (subject, key, request_hash, created_at)
+    VALUES (@subject, @key, @request_hash, NOW())
+ON CONFLICT (subject, key)
+    DO UPDATE SET
+        request_hash = EXCLUDED.request_hash,
+        response_status = NULL,
+        content_type = NULL,
+        response_body = NULL,
+        created_at = EXCLUDED.created_at,
+        completed_at = NULL
+    WHERE
+        idempotency_keys.created_at < NOW() - make_interval(secs => @ttl_seconds::float8);
+
+-- name: GetIdempotencyKey :one
+SELECT
+    *
+FROM
+    idempotency_keys
+WHERE
+    subject = $1
+    AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE
+    idempotency_keys
+SET
+    response_status = $3,
+    content_type = $4,
+    response_body = $5,
+    completed_at = NOW()
+WHERE
+    subject = $1
+    AND key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE subject = $1
+    AND key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < NOW() - make_interval(secs => @ttl_seconds::float8);
+
@@ -0,0 +1,47 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, actor_id, chirp_id, conversation_id)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, $3, $4, $5)
+RETURNING
+    *;
+
+-- name: GetNotification :one
+SELECT
+    *
+FROM
+    notifications
+WHERE
+    id = $1;
+
+-- name: GetNotifications :many
+SELECT
+    *
+FROM
+    notifications
+WHERE
+    user_id = @user_id
+    AND (NOT @unread_only::boolean
+        OR read_at IS NULL)
+    AND created_at < COALESCE(sqlc.narg('before')::timestamp, NOW())
+ORDER BY
+    created_at DESC
+LIMIT @page_size;
+
+-- name: MarkNotificationRead :execrows
+UPDATE
+    notifications
+SET
+    read_at = COALESCE(read_at, NOW()),
+    updated_at = NOW()
+WHERE
+    id = $1
+    AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE
+    notifications
+SET
+    read_at = NOW(),
+    updated_at = NOW()
+WHERE
+    user_id = $1
+    AND read_at IS NULL;
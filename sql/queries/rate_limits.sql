@@ -0,0 +1,28 @@
+-- name: CreateRateLimit :exec
+INSERT INTO rate_limits (key, tokens, updated_at)
+    VALUES ($1, $2, $3)
+ON CONFLICT
+    DO NOTHING;
+
+-- name: GetRateLimitForUpdate :one
+SELECT
+    *
+FROM
+    rate_limits
+WHERE
+    key = $1
+FOR UPDATE;
+
+-- name: UpdateRateLimit :exec
+UPDATE
+    rate_limits
+SET
+    tokens = $2,
+    updated_at = $3
+WHERE
+    key = $1;
+
+-- name: DeleteStaleRateLimits :execrows
+DELETE FROM rate_limits
+WHERE updated_at < $1;
+
@@ -12,7 +12,7 @@ FROM
 WHERE
     token = $1;
 
--- name: RevokeRefreshToken :exec
+-- name: RevokeRefreshToken :execrows
 UPDATE
     refresh_tokens
 SET
@@ -21,3 +21,23 @@ SET
 WHERE
     token = $1;
 
+-- name: RevokeRefreshTokensForUser :exec
+UPDATE
+    refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE
+    user_id = $1
+    AND revoked_at IS NULL;
+
+-- name: GetRefreshTokensForUser :many
+SELECT
+    *
+FROM
+    refresh_tokens
+WHERE
+    user_id = $1
+ORDER BY
+    created_at ASC;
+
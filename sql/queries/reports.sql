@@ -0,0 +1,52 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, reported_user_id, chirp_id, reason, details, status)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, $3, $4, $5, 'open')
+RETURNING
+    *;
+
+-- name: GetReport :one
+SELECT
+    *
+FROM
+    reports
+WHERE
+    id = $1;
+
+-- name: GetReports :many
+SELECT
+    *
+FROM
+    reports
+WHERE
+    status = $1
+ORDER BY
+    created_at ASC
+LIMIT $2;
+
+-- name: ResolveReport :execrows
+UPDATE
+    reports
+SET
+    status = $2,
+    resolved_at = NOW(),
+    resolved_by = $3,
+    updated_at = NOW()
+WHERE
+    id = $1
+    AND status = 'open';
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, report_id, action, target_user_id, target_chirp_id, note)
+    VALUES (gen_random_uuid (), NOW(), $1, $2, $3, $4, $5, $6)
+RETURNING
+    *;
+
+-- name: GetModerationActions :many
+SELECT
+    *
+FROM
+    moderation_actions
+ORDER BY
+    created_at DESC
+LIMIT $1;
+
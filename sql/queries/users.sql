@@ -30,7 +30,7 @@ RETURNING
     email,
     is_chirpy_red;
 
--- name: UpgradeUser :exec
+-- name: UpgradeUser :execrows
 UPDATE
     users
 SET
@@ -38,3 +38,111 @@ SET
 WHERE
     id = $1;
 
+-- name: CountUsers :one
+SELECT
+    COUNT(*)
+FROM
+    users
+WHERE
+    id = ANY (@ids::uuid[]);
+
+-- name: GetUserByID :one
+SELECT
+    *
+FROM
+    users
+WHERE
+    id = $1;
+
+-- name: SetUserModerator :exec
+UPDATE
+    users
+SET
+    is_moderator = $2,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: SetUserStatus :exec
+UPDATE
+    users
+SET
+    status = $2,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: GetUserStatus :one
+SELECT
+    status
+FROM
+    users
+WHERE
+    id = $1;
+
+-- name: DeactivateUser :exec
+UPDATE
+    users
+SET
+    status = 'deactivated',
+    deactivated_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: ReactivateUser :exec
+UPDATE
+    users
+SET
+    status = 'active',
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: GetUsersDueForDeletion :many
+SELECT
+    id
+FROM
+    users
+WHERE
+    status = 'deactivated'
+    AND deactivated_at <= NOW() - make_interval(secs => @grace_seconds::float8)
+LIMIT @batch_size::int
+FOR UPDATE
+    SKIP LOCKED;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: AnonymizeUser :exec
+UPDATE
+    users
+SET
+    email = 'deleted+' || id || '@chirpy.invalid',
+    hashed_password = '',
+    is_chirpy_red = FALSE,
+    is_moderator = FALSE,
+    status = 'deleted',
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: DeleteUserPersonalData :exec
+WITH deleted_tokens AS (
+    DELETE FROM refresh_tokens
+    WHERE refresh_tokens.user_id = $1),
+deleted_webhooks AS (
+    DELETE FROM webhooks
+    WHERE webhooks.user_id = $1),
+deleted_notifications AS (
+    DELETE FROM notifications
+    WHERE notifications.user_id = $1),
+deleted_blocks AS (
+    DELETE FROM blocks
+    WHERE blocker_id = $1)
+DELETE FROM mutes
+WHERE muter_id = $1;
+
@@ -0,0 +1,138 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, events)
+    VALUES (gen_random_uuid (), NOW(), NOW(), $1, $2, $3, $4)
+RETURNING
+    *;
+
+-- name: GetWebhook :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    url,
+    events
+FROM
+    webhooks
+WHERE
+    id = $1;
+
+-- name: GetWebhooksForUser :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    url,
+    events
+FROM
+    webhooks
+WHERE
+    user_id = $1
+ORDER BY
+    created_at ASC;
+
+-- name: GetAdminWebhooks :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    url,
+    events
+FROM
+    webhooks
+WHERE
+    user_id IS NULL
+ORDER BY
+    created_at ASC;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, event, payload, status, attempts, next_attempt_at, traceparent)
+SELECT
+    gen_random_uuid (),
+    NOW(),
+    NOW(),
+    webhooks.id,
+    @event::text,
+    @payload::jsonb,
+    'pending',
+    0,
+    NOW(),
+    sqlc.narg('traceparent')::text
+FROM
+    webhooks
+WHERE
+    @event::text = ANY (webhooks.events)
+    AND (webhooks.user_id IS NULL
+        OR webhooks.user_id = @actor_id::uuid);
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE
+    webhook_deliveries
+SET
+    next_attempt_at = NOW() + make_interval(secs => @lease_seconds::float8),
+    updated_at = NOW()
+FROM
+    webhooks
+WHERE
+    webhooks.id = webhook_deliveries.webhook_id
+    AND webhook_deliveries.id IN (
+        SELECT
+            id
+        FROM
+            webhook_deliveries
+        WHERE
+            status = 'pending'
+            AND next_attempt_at <= NOW()
+        ORDER BY
+            next_attempt_at ASC
+        LIMIT @batch_size::int
+        FOR UPDATE
+            SKIP LOCKED)
+RETURNING
+    webhook_deliveries.id,
+    webhook_deliveries.event,
+    webhook_deliveries.payload,
+    webhook_deliveries.attempts,
+    webhook_deliveries.traceparent,
+    webhooks.url,
+    webhooks.secret;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE
+    webhook_deliveries
+SET
+    status = @status,
+    attempts = @attempts,
+    next_attempt_at = NOW() + make_interval(secs => @retry_after_seconds::float8),
+    last_status_code = @last_status_code,
+    last_error = @last_error,
+    updated_at = NOW()
+WHERE
+    id = @id;
+
+-- name: GetWebhookDeliveries :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    webhook_id,
+    event,
+    payload,
+    status,
+    attempts,
+    next_attempt_at,
+    last_status_code,
+    last_error
+FROM
+    webhook_deliveries
+WHERE
+    webhook_id = $1
+ORDER BY
+    created_at DESC
+LIMIT $2;
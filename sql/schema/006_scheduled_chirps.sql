@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE chirps
+    ADD COLUMN publish_at timestamp,
+    ADD COLUMN is_published boolean NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE chirps
+    DROP COLUMN publish_at,
+    DROP COLUMN is_published;
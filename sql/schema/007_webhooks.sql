@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    user_id uuid REFERENCES users (id) ON DELETE CASCADE,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    webhook_id uuid NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL,
+    attempts integer NOT NULL,
+    next_attempt_at timestamp NOT NULL,
+    last_status_code integer,
+    last_error text
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at)
+WHERE
+    status = 'pending';
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+
+DROP TABLE webhooks;
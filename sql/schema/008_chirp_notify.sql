@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_chirp_published ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    IF NEW.is_published AND (TG_OP = 'INSERT' OR NOT OLD.is_published) THEN
+        PERFORM
+            pg_notify('chirp_published', NEW.id::text);
+    END IF;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirp_published
+    AFTER INSERT OR UPDATE OF is_published ON chirps
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_chirp_published ();
+
+-- +goose Down
+DROP TRIGGER chirp_published ON chirps;
+
+DROP FUNCTION notify_chirp_published ();
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE notifications (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type text NOT NULL,
+    actor_id uuid REFERENCES users (id) ON DELETE CASCADE,
+    chirp_id uuid REFERENCES chirps (id) ON DELETE CASCADE,
+    read_at timestamp
+);
+
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at DESC);
+
+-- +goose StatementBegin
+CREATE FUNCTION notify_notification_created ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    PERFORM
+        pg_notify('notification_created', NEW.id::text);
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER notification_created
+    AFTER INSERT ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_notification_created ();
+
+-- +goose Down
+DROP TRIGGER notification_created ON notifications;
+
+DROP FUNCTION notify_notification_created ();
+
+DROP TABLE notifications;
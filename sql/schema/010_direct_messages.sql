@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE conversations (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE TABLE conversation_members (
+    conversation_id uuid NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    last_read_at timestamp,
+    muted boolean NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_members_user_id_idx ON conversation_members (user_id);
+
+CREATE TABLE messages (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    conversation_id uuid NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    body text NOT NULL
+);
+
+CREATE INDEX messages_conversation_id_created_at_idx ON messages (conversation_id, created_at DESC);
+
+ALTER TABLE notifications
+    ADD COLUMN conversation_id uuid REFERENCES conversations (id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE notifications
+    DROP COLUMN conversation_id;
+
+DROP TABLE messages;
+
+DROP TABLE conversation_members;
+
+DROP TABLE conversations;
+
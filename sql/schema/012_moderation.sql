@@ -0,0 +1,47 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN is_moderator boolean NOT NULL DEFAULT FALSE,
+    ADD COLUMN status text NOT NULL DEFAULT 'active';
+
+ALTER TABLE chirps
+    ADD COLUMN hidden_at timestamp;
+
+CREATE TABLE reports (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    reporter_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reported_user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    chirp_id uuid REFERENCES chirps (id) ON DELETE CASCADE,
+    reason text NOT NULL,
+    details text NOT NULL,
+    status text NOT NULL,
+    resolved_at timestamp,
+    resolved_by uuid REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at);
+
+CREATE TABLE moderation_actions (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    moderator_id uuid REFERENCES users (id) ON DELETE SET NULL,
+    report_id uuid REFERENCES reports (id) ON DELETE SET NULL,
+    action text NOT NULL,
+    target_user_id uuid,
+    target_chirp_id uuid,
+    note text NOT NULL
+);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+
+DROP TABLE reports;
+
+ALTER TABLE chirps
+    DROP COLUMN hidden_at;
+
+ALTER TABLE users
+    DROP COLUMN is_moderator,
+    DROP COLUMN status;
+
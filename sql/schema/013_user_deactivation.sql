@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN deactivated_at timestamp;
+
+CREATE INDEX users_deactivated_at_idx ON users (deactivated_at)
+WHERE
+    status = 'deactivated';
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN deactivated_at;
+
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE export_jobs (
+    id uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    requested_by uuid REFERENCES users (id) ON DELETE SET NULL,
+    status text NOT NULL,
+    archive bytea,
+    error text,
+    completed_at timestamp,
+    expires_at timestamp
+);
+
+CREATE INDEX export_jobs_pending_idx ON export_jobs (created_at)
+WHERE
+    status = 'pending';
+
+-- +goose Down
+DROP TABLE export_jobs;
+
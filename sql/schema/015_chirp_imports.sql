@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_imports (
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    external_id text NOT NULL,
+    chirp_id uuid REFERENCES chirps (id) ON DELETE SET NULL,
+    created_at timestamp NOT NULL,
+    PRIMARY KEY (user_id, external_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_imports;
+
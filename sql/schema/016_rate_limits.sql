@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE rate_limits (
+    key text PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+-- +goose Down
+DROP TABLE rate_limits;
+
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE idempotency_keys (
+    subject text NOT NULL,
+    key text NOT NULL,
+    request_hash text NOT NULL,
+    response_status integer,
+    content_type text,
+    response_body bytea,
+    created_at timestamp NOT NULL,
+    completed_at timestamp,
+    PRIMARY KEY (subject, key)
+);
+
+-- +goose Down
+DROP TABLE idempotency_keys;
+
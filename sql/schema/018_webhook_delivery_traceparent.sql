@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE webhook_deliveries
+    ADD COLUMN traceparent text;
+
+-- +goose Down
+ALTER TABLE webhook_deliveries
+    DROP COLUMN traceparent;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE chirps
+    DROP CONSTRAINT chirps_body_key;
+
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+
+DROP INDEX chirps_user_id_created_at_idx;
+
+ALTER TABLE chirps
+    ADD CONSTRAINT chirps_body_key UNIQUE (body);
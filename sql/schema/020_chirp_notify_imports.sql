@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Imported chirps are old, so they are not streamed as newly published.
+-- Imports set chirpy.importing for their transaction.
+CREATE OR REPLACE FUNCTION notify_chirp_published ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    IF NEW.is_published AND (TG_OP = 'INSERT' OR NOT OLD.is_published) AND COALESCE(current_setting('chirpy.importing', TRUE), '') <> 'on' THEN
+        PERFORM
+            pg_notify('chirp_published', NEW.id::text);
+    END IF;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_published ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    IF NEW.is_published AND (TG_OP = 'INSERT' OR NOT OLD.is_published) THEN
+        PERFORM
+            pg_notify('chirp_published', NEW.id::text);
+    END IF;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +goose StatementEnd
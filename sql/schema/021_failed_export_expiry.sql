@@ -0,0 +1,17 @@
+-- +goose Up
+-- Failed jobs used to be kept forever.
+UPDATE
+    export_jobs
+SET
+    expires_at = completed_at + INTERVAL '7 days'
+WHERE
+    status = 'failed'
+    AND expires_at IS NULL;
+
+-- +goose Down
+UPDATE
+    export_jobs
+SET
+    expires_at = NULL
+WHERE
+    status = 'failed';
@@ -0,0 +1,128 @@
+-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, publish_at, is_published)
+    VALUES (gen_random_uuid (), CAST(unixepoch ('subsec') * 1000000 AS INTEGER), CAST(unixepoch ('subsec') * 1000000 AS INTEGER), ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetChirps :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    is_published
+    AND hidden_at IS NULL
+ORDER BY
+    CASE WHEN CAST(sqlc.arg(sort) AS TEXT) = 'desc' THEN
+        - created_at
+    ELSE
+        created_at
+    END;
+
+-- name: GetChirpsFromAuthor :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    user_id = sqlc.arg(user_id)
+    AND is_published
+    AND hidden_at IS NULL
+ORDER BY
+    CASE WHEN CAST(sqlc.arg(sort) AS TEXT) = 'desc' THEN
+        - created_at
+    ELSE
+        created_at
+    END;
+
+-- name: GetChirp :one
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    id = ?
+    AND is_published;
+
+-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = ?;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = ?
+    AND NOT is_published;
+
+-- name: GetScheduledChirps :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    user_id = ?
+    AND NOT is_published
+ORDER BY
+    publish_at ASC;
+
+-- name: GetScheduledChirp :one
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    id = ?
+    AND NOT is_published;
+
+-- name: UpdateScheduledChirp :one
+UPDATE
+    chirps
+SET
+    body = sqlc.arg(body),
+    publish_at = sqlc.arg(publish_at),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = sqlc.arg(id)
+    AND NOT is_published
+RETURNING
+    *;
+
+-- name: GetDueChirps :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    NOT is_published
+    AND publish_at <= CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+ORDER BY
+    publish_at ASC
+LIMIT ?;
+
+-- name: PublishChirp :exec
+UPDATE
+    chirps
+SET
+    is_published = TRUE,
+    created_at = publish_at,
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = ?;
+
+-- name: HideChirp :exec
+UPDATE
+    chirps
+SET
+    hidden_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = ?;
+
+-- name: GetAllChirpsForUser :many
+SELECT
+    *
+FROM
+    chirps
+WHERE
+    user_id = ?
+ORDER BY
+    created_at ASC;
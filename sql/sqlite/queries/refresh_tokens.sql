@@ -0,0 +1,42 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+    VALUES (?, CAST(unixepoch ('subsec') * 1000000 AS INTEGER), CAST(unixepoch ('subsec') * 1000000 AS INTEGER), ?, ?)
+RETURNING
+    *;
+
+-- name: GetRefreshToken :one
+SELECT
+    *
+FROM
+    refresh_tokens
+WHERE
+    token = ?;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE
+    refresh_tokens
+SET
+    revoked_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    token = ?;
+
+-- name: RevokeRefreshTokensForUser :exec
+UPDATE
+    refresh_tokens
+SET
+    revoked_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    user_id = ?
+    AND revoked_at IS NULL;
+
+-- name: GetRefreshTokensForUser :many
+SELECT
+    *
+FROM
+    refresh_tokens
+WHERE
+    user_id = ?
+ORDER BY
+    created_at ASC;
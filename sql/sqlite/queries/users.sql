@@ -0,0 +1,93 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+    VALUES (gen_random_uuid (), CAST(unixepoch ('subsec') * 1000000 AS INTEGER), CAST(unixepoch ('subsec') * 1000000 AS INTEGER), ?, ?, FALSE)
+RETURNING
+    id, created_at, updated_at, email, is_chirpy_red;
+
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
+
+-- name: GetUserByEmail :one
+SELECT
+    *
+FROM
+    users
+WHERE
+    email = ?;
+
+-- name: UpdateUser :one
+UPDATE
+    users
+SET
+    email = sqlc.arg(email),
+    hashed_password = sqlc.arg(hashed_password)
+WHERE
+    id = sqlc.arg(id)
+RETURNING
+    id,
+    created_at,
+    updated_at,
+    email,
+    is_chirpy_red;
+
+-- name: UpgradeUser :execrows
+UPDATE
+    users
+SET
+    is_chirpy_red = TRUE
+WHERE
+    id = ?;
+
+-- name: GetUserByID :one
+SELECT
+    *
+FROM
+    users
+WHERE
+    id = ?;
+
+-- name: SetUserModerator :exec
+UPDATE
+    users
+SET
+    is_moderator = sqlc.arg(is_moderator),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = sqlc.arg(id);
+
+-- name: SetUserStatus :exec
+UPDATE
+    users
+SET
+    status = sqlc.arg(status),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = sqlc.arg(id);
+
+-- name: GetUserStatus :one
+SELECT
+    status
+FROM
+    users
+WHERE
+    id = ?;
+
+-- name: DeactivateUser :exec
+UPDATE
+    users
+SET
+    status = 'deactivated',
+    deactivated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER),
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = ?;
+
+-- name: ReactivateUser :exec
+UPDATE
+    users
+SET
+    status = 'active',
+    deactivated_at = NULL,
+    updated_at = CAST(unixepoch ('subsec') * 1000000 AS INTEGER)
+WHERE
+    id = ?;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- The users, chirps and refresh tokens of sql/schema as they stand at 019.
+-- UUIDs are stored as text and timestamps as integer microseconds since the
+-- Unix epoch, which is how internal/sqlite opens the database.
+CREATE TABLE users (
+    id text PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    email text UNIQUE NOT NULL,
+    hashed_password text NOT NULL,
+    is_chirpy_red boolean NOT NULL,
+    is_moderator boolean NOT NULL DEFAULT FALSE,
+    status text NOT NULL DEFAULT 'active',
+    deactivated_at timestamp
+);
+
+CREATE TABLE chirps (
+    id text PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    body text NOT NULL,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    publish_at timestamp,
+    is_published boolean NOT NULL DEFAULT TRUE,
+    hidden_at timestamp
+);
+
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at);
+
+CREATE TABLE refresh_tokens (
+    token text PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp
+);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- +goose Down
+DROP TABLE refresh_tokens;
+
+DROP TABLE chirps;
+
+DROP TABLE users;
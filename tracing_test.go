@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/debobrad579/chirpy/internal/database"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans installs a tracer provider that keeps every span in memory for
+// the rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestMiddlewareTracing(t *testing.T) {
+	recorder := recordSpans(t)
+	logs := captureLogs(t)
+
+	cfg := &apiConfig{}
+	inner := http.NewServeMux()
+	inner.HandleFunc("GET /chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", logRoute(inner)))
+	handler := middlewareTracing(cfg.middlewareRequestLog(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chirps/123", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, expected 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/chirps/{chirpID}" {
+		t.Fatalf("span is named %q, expected the route", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("span kind is %s, expected server", span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("span is in trace %s, expected the one from traceparent", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("span's parent is %s, expected the one from traceparent", got)
+	}
+
+	records := logs()
+	if len(records) != 1 || records[0]["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("request was not logged with its trace ID: %v", records)
+	}
+}
+
+func TestDeliverWebhookPropagatesTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var received string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// The traceparent stored with the delivery when the request queued it.
+	ctx, span := otel.Tracer(tracerName).Start(withTraceparent(context.Background(), testTraceparent), "request")
+	stored := traceparent(ctx)
+	span.End()
+
+	status, err := deliverWebhook(context.Background(), receiver.Client(), database.ClaimWebhookDeliveriesRow{
+		ID:          uuid.New(),
+		Event:       "chirp.created",
+		Payload:     json.RawMessage(`{}`),
+		Traceparent: sql.NullString{String: stored, Valid: true},
+		Url:         receiver.URL,
+		Secret:      "secret",
+	})
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("deliverWebhook returned %d, %v", status, err)
+	}
+
+	var delivery sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "deliver webhook" {
+			delivery = s
+		}
+	}
+	if delivery == nil {
+		t.Fatal("no span was recorded for the delivery")
+	}
+	if delivery.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Fatal("delivery span is not a child of the request that queued it")
+	}
+
+	sc := withTraceparent(context.Background(), received)
+	if got := trace.SpanContextFromContext(sc); got.TraceID() != delivery.SpanContext().TraceID() || got.SpanID() != delivery.SpanContext().SpanID() {
+		t.Fatalf("receiver got traceparent %q, expected the delivery span", received)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// runWorker calls work every interval until ctx is cancelled. Each tick keeps
+// calling work while it reports a full batch, so a backlog drains without
+// waiting for the next tick. The outcome of each tick is reported to
+// cfg.workers for /readyz.
+func (cfg *apiConfig) runWorker(ctx context.Context, name string, interval time.Duration, batchSize int, work func(context.Context) (int, error)) {
+	cfg.workers.register(name, workerStallIntervals*interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var err error
+			for {
+				var n int
+				n, err = work(ctx)
+				if err != nil {
+					slog.Error("Error running worker", "worker", name, "error", err)
+					break
+				}
+				if n < batchSize {
+					break
+				}
+			}
+			cfg.workers.report(name, err)
+		}
+	}
+}